@@ -0,0 +1,176 @@
+// Package ectype defines the EtherCAT data types and mailbox protocols
+// without depending on SOEM, so description parsers such as esi can be built
+// and tested without it.
+package ectype
+
+import (
+	"fmt"
+	"strings"
+)
+
+type DataType uint16
+
+const (
+	ECT_BOOLEAN         DataType = 0x0001
+	ECT_INTEGER8        DataType = 0x0002
+	ECT_INTEGER16       DataType = 0x0003
+	ECT_INTEGER32       DataType = 0x0004
+	ECT_UNSIGNED8       DataType = 0x0005
+	ECT_UNSIGNED16      DataType = 0x0006
+	ECT_UNSIGNED32      DataType = 0x0007
+	ECT_REAL32          DataType = 0x0008
+	ECT_VISIBLE_STRING  DataType = 0x0009
+	ECT_OCTET_STRING    DataType = 0x000A
+	ECT_UNICODE_STRING  DataType = 0x000B
+	ECT_TIME_OF_DAY     DataType = 0x000C
+	ECT_TIME_DIFFERENCE DataType = 0x000D
+	ECT_DOMAIN          DataType = 0x000F
+	ECT_INTEGER24       DataType = 0x0010
+	ECT_REAL64          DataType = 0x0011
+	ECT_INTEGER64       DataType = 0x0015
+	ECT_UNSIGNED24      DataType = 0x0016
+	ECT_UNSIGNED64      DataType = 0x001B
+	ECT_BIT1            DataType = 0x0030
+	ECT_BIT2            DataType = 0x0031
+	ECT_BIT3            DataType = 0x0032
+	ECT_BIT4            DataType = 0x0033
+	ECT_BIT5            DataType = 0x0034
+	ECT_BIT6            DataType = 0x0035
+	ECT_BIT7            DataType = 0x0036
+	ECT_BIT8            DataType = 0x0037
+)
+
+// Size in bytes of a value of this type, or 0 if the type is variable length
+func (t DataType) Size() int {
+	switch t {
+	case ECT_BOOLEAN, ECT_INTEGER8, ECT_UNSIGNED8,
+		ECT_BIT1, ECT_BIT2, ECT_BIT3, ECT_BIT4,
+		ECT_BIT5, ECT_BIT6, ECT_BIT7, ECT_BIT8:
+		return 1
+	case ECT_INTEGER16, ECT_UNSIGNED16:
+		return 2
+	case ECT_INTEGER24, ECT_UNSIGNED24:
+		return 3
+	case ECT_INTEGER32, ECT_UNSIGNED32, ECT_REAL32:
+		return 4
+	case ECT_INTEGER64, ECT_UNSIGNED64, ECT_REAL64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+func (t DataType) String() string {
+	switch t {
+	case ECT_BOOLEAN:
+		return "BOOLEAN"
+	case ECT_INTEGER8:
+		return "INTEGER8"
+	case ECT_INTEGER16:
+		return "INTEGER16"
+	case ECT_INTEGER24:
+		return "INTEGER24"
+	case ECT_INTEGER32:
+		return "INTEGER32"
+	case ECT_INTEGER64:
+		return "INTEGER64"
+	case ECT_UNSIGNED8:
+		return "UNSIGNED8"
+	case ECT_UNSIGNED16:
+		return "UNSIGNED16"
+	case ECT_UNSIGNED24:
+		return "UNSIGNED24"
+	case ECT_UNSIGNED32:
+		return "UNSIGNED32"
+	case ECT_UNSIGNED64:
+		return "UNSIGNED64"
+	case ECT_REAL32:
+		return "REAL32"
+	case ECT_REAL64:
+		return "REAL64"
+	case ECT_VISIBLE_STRING:
+		return "VISIBLE_STRING"
+	case ECT_OCTET_STRING:
+		return "OCTET_STRING"
+	case ECT_UNICODE_STRING:
+		return "UNICODE_STRING"
+	case ECT_TIME_OF_DAY:
+		return "TIME_OF_DAY"
+	case ECT_TIME_DIFFERENCE:
+		return "TIME_DIFFERENCE"
+	case ECT_DOMAIN:
+		return "DOMAIN"
+	case ECT_BIT1, ECT_BIT2, ECT_BIT3, ECT_BIT4,
+		ECT_BIT5, ECT_BIT6, ECT_BIT7, ECT_BIT8:
+		return fmt.Sprintf("BIT%d", int(t-ECT_BIT1)+1)
+	default:
+		return fmt.Sprintf("0x%04x", uint16(t))
+	}
+}
+
+// Parses a data type named as in ESI and ENI files, either by its IEC 61131
+// name (BOOL, UINT, DINT, ...) or its CoE name (UNSIGNED16, ...)
+func ParseDataType(name string) (DataType, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if strings.HasPrefix(name, "STRING(") {
+		return ECT_VISIBLE_STRING, true
+	}
+
+	switch name {
+	case "BOOL", "BOOLEAN", "BIT":
+		return ECT_BOOLEAN, true
+	case "SINT", "INTEGER8":
+		return ECT_INTEGER8, true
+	case "INT", "INTEGER16":
+		return ECT_INTEGER16, true
+	case "INT24", "INTEGER24":
+		return ECT_INTEGER24, true
+	case "DINT", "INTEGER32":
+		return ECT_INTEGER32, true
+	case "LINT", "INTEGER64":
+		return ECT_INTEGER64, true
+	case "USINT", "BYTE", "UNSIGNED8":
+		return ECT_UNSIGNED8, true
+	case "UINT", "WORD", "UNSIGNED16":
+		return ECT_UNSIGNED16, true
+	case "UINT24", "UNSIGNED24":
+		return ECT_UNSIGNED24, true
+	case "UDINT", "DWORD", "UNSIGNED32":
+		return ECT_UNSIGNED32, true
+	case "ULINT", "LWORD", "UNSIGNED64":
+		return ECT_UNSIGNED64, true
+	case "REAL", "REAL32", "FLOAT":
+		return ECT_REAL32, true
+	case "LREAL", "REAL64", "DOUBLE":
+		return ECT_REAL64, true
+	case "STRING", "VISIBLE_STRING":
+		return ECT_VISIBLE_STRING, true
+	case "OCTET_STRING":
+		return ECT_OCTET_STRING, true
+	case "UNICODE_STRING":
+		return ECT_UNICODE_STRING, true
+	case "TIME_OF_DAY":
+		return ECT_TIME_OF_DAY, true
+	case "TIME_DIFFERENCE":
+		return ECT_TIME_DIFFERENCE, true
+	case "DOMAIN":
+		return ECT_DOMAIN, true
+	}
+
+	var bits int
+	if n, _ := fmt.Sscanf(name, "BIT%d", &bits); n == 1 && bits >= 1 && bits <= 8 {
+		return ECT_BIT1 + DataType(bits-1), true
+	}
+	return 0, false
+}
+
+type MailboxProtocol uint16
+
+const (
+	ECT_MBXPROT_AOE MailboxProtocol = 0x0001
+	ECT_MBXPROT_EOE MailboxProtocol = 0x0002
+	ECT_MBXPROT_COE MailboxProtocol = 0x0004
+	ECT_MBXPROT_FOE MailboxProtocol = 0x0008
+	ECT_MBXPROT_SOE MailboxProtocol = 0x0010
+	ECT_MBXPROT_VOE MailboxProtocol = 0x0020
+)
@@ -0,0 +1,317 @@
+package eni
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/siyka-au/go-soem/esi/bind"
+	"github.com/siyka-au/go-soem/soem"
+)
+
+// The expected network described by the ENI, for Master.SetExpectedNetwork
+func (c *Config) Network() *soem.NetworkConfig {
+	network := &soem.NetworkConfig{}
+	for _, s := range c.Slaves {
+		network.Slaves = append(network.Slaves, soem.ExpectedSlave{
+			Name:        s.Info.Name,
+			VendorID:    uint32(s.Info.VendorID),
+			ProductCode: uint32(s.Info.ProductCode),
+			Revision:    uint32(s.Info.RevisionNo),
+		})
+	}
+	return network
+}
+
+// Configures master from the ENI. Must be called after ConfigInit and
+// before ConfigMap. The discovered slaves must match the ENI, and the ENI
+// is set as the master's expected network.
+//
+// For each slave, PRE_OP to SAFE_OP init commands, the PDO assignment and
+// the DC sync settings are applied every time the slave is brought to
+// SAFE_OP. SOEM sets up mailboxes, sync managers, FMMUs, DC and the state
+// machine itself, so register init commands for those ranges are not
+// replayed. Apply fails, naming the commands, if the ENI has any other init
+// command that would not be run.
+func (c *Config) Apply(m *soem.Master) error {
+	network := c.Network()
+	if err := m.ValidateNetwork(network); err != nil {
+		return err
+	}
+	for i := range c.Slaves {
+		if err := c.Slaves[i].checkInitCmds(); err != nil {
+			return fmt.Errorf("slave %d %s: %w", i+1, c.Slaves[i].Info.Name, err)
+		}
+	}
+	m.SetExpectedNetwork(network)
+
+	for i := range c.Slaves {
+		s := &c.Slaves[i]
+		position := uint16(i + 1)
+
+		if mapping, ok := s.pdoMapping(); ok {
+			m.SetPDOMapping(position, mapping)
+		}
+
+		m.OnPreOpToSafeOp(position, func(slave *soem.Slave) error {
+			return s.preOpToSafeOp(m, slave.Position)
+		})
+	}
+
+	return nil
+}
+
+// The PDO assignment of a slave with a CoE mailbox whose ENI writes its
+// assignment objects. Slaves with fixed assignments have none.
+func (s *Slave) pdoMapping() (soem.PDOMapping, bool) {
+	if s.CoE == nil {
+		return soem.PDOMapping{}, false
+	}
+
+	assigned := false
+	for _, cmd := range s.CoE.InitCmds {
+		if cmd.Index == 0x1C12 || cmd.Index == 0x1C13 {
+			assigned = true
+		}
+	}
+	if !assigned {
+		return soem.PDOMapping{}, false
+	}
+
+	return bind.PDOMapping(s.ProcessData.RxPDOs, s.ProcessData.TxPDOs), true
+}
+
+// PDO assignment and mapping objects, written from the ENI's PDO list
+func isPDOObject(index Number) bool {
+	return index == 0x1C12 || index == 0x1C13 ||
+		(index >= 0x1600 && index < 0x1800) ||
+		(index >= 0x1A00 && index < 0x1C00)
+}
+
+// Registers SOEM configures itself: station address, AL control and
+// status, SII, FMMUs, sync managers and distributed clocks
+func isManagedRegister(ado Number) bool {
+	return (ado >= 0x0010 && ado < 0x0014) ||
+		(ado >= 0x0120 && ado < 0x0136) ||
+		(ado >= 0x0500 && ado < 0x0A00)
+}
+
+// Returns an error naming every init command Apply would not run. Only
+// PRE_OP to SAFE_OP CoE downloads and register writes addressed to the slave
+// itself are replayed. Register commands SOEM covers with its own
+// configuration are skipped in any transition.
+func (s *Slave) checkInitCmds() error {
+	var unsupported []string
+	reject := func(kind, comment string, transitions []string, reason string) {
+		unsupported = append(unsupported, fmt.Sprintf("%s init command %q (%s): %s",
+			kind, comment, strings.Join(transitions, ","), reason))
+	}
+
+	if s.CoE != nil {
+		for _, cmd := range s.CoE.InitCmds {
+			switch {
+			case !hasTransition(cmd.Transitions, "PS"):
+				reject("CoE", cmd.Comment, cmd.Transitions, "only PRE_OP to SAFE_OP commands are run")
+			case cmd.Ccs != CCS_DOWNLOAD:
+				reject("CoE", cmd.Comment, cmd.Transitions, "uploads are not checked")
+			}
+		}
+	}
+
+	for _, cmd := range s.InitCmds {
+		if isManagedRegister(cmd.Ado) {
+			continue
+		}
+		switch {
+		case !hasTransition(cmd.Transitions, "PS"):
+			reject("register", cmd.Comment, cmd.Transitions, "only PRE_OP to SAFE_OP commands are run")
+		case cmd.Cmd == CMD_BWR:
+			reject("register", cmd.Comment, cmd.Transitions, "broadcast writes would reach every slave")
+		case cmd.Cmd == CMD_FPWR && cmd.Adp != s.Info.PhysAddr:
+			reject("register", cmd.Comment, cmd.Transitions,
+				fmt.Sprintf("writes station 0x%04x of another slave", uint32(cmd.Adp)))
+		case cmd.Cmd != CMD_APWR && cmd.Cmd != CMD_FPWR:
+			reject("register", cmd.Comment, cmd.Transitions,
+				fmt.Sprintf("command %d is not a write", uint32(cmd.Cmd)))
+		}
+	}
+
+	if len(unsupported) > 0 {
+		return fmt.Errorf("unsupported init commands:\n  %s", strings.Join(unsupported, "\n  "))
+	}
+	return nil
+}
+
+// The slave addressed by an auto increment address, which counts down from 0
+// at the first slave
+func autoIncPosition(adp Number) uint16 {
+	return uint16(-int16(adp)) + 1
+}
+
+func (s *Slave) preOpToSafeOp(m *soem.Master, position uint16) error {
+	if s.CoE != nil {
+		_, pdoMapped := s.pdoMapping()
+		for _, cmd := range s.CoE.InitCmds {
+			if !hasTransition(cmd.Transitions, "PS") || cmd.Ccs != CCS_DOWNLOAD {
+				continue
+			}
+			if pdoMapped && isPDOObject(cmd.Index) {
+				continue
+			}
+
+			var err error
+			if cmd.CompleteAccess {
+				err = m.SDOWriteCA(position, uint16(cmd.Index), uint8(cmd.SubIndex), cmd.Data)
+			} else {
+				err = m.SDOWrite(position, uint16(cmd.Index), uint8(cmd.SubIndex), cmd.Data)
+			}
+			if err != nil {
+				return fmt.Errorf("init command %q: %w", cmd.Comment, err)
+			}
+		}
+	}
+
+	for _, cmd := range s.InitCmds {
+		if !hasTransition(cmd.Transitions, "PS") || isManagedRegister(cmd.Ado) {
+			continue
+		}
+		target := position
+		switch cmd.Cmd {
+		case CMD_APWR:
+			target = autoIncPosition(cmd.Adp)
+		case CMD_FPWR:
+			// checked to address this slave
+		default:
+			continue
+		}
+		if err := m.WriteRegister(target, uint16(cmd.Ado), cmd.Data); err != nil {
+			return fmt.Errorf("init command %q: %w", cmd.Comment, err)
+		}
+	}
+
+	if s.DC != nil && s.DC.CycleTime0 != 0 {
+		if s.DC.CycleTime1 != 0 {
+			m.DCSync01(position, s.DC.Sync0Cycle(), s.DC.Sync1Cycle(), s.DC.Shift())
+		} else {
+			m.DCSync0(position, s.DC.Sync0Cycle(), s.DC.Shift())
+		}
+	}
+
+	return nil
+}
+
+// Builds each slave's variables from its assigned PDOs, named as in the
+// ENI and placed where the ENI's process image lists them. Must be called
+// after ConfigMap. Fails if SOEM mapped a slave with a different process
+// data size or at a different offset than the ENI describes. The ENI images
+// may start at a non-zero bit, so offsets are compared relative to the
+// lowest BitStart of each image.
+func (c *Config) BindVariables(m *soem.Master) error {
+	outputBase, inputBase := c.imageBases()
+
+	for i := range c.Slaves {
+		s := &c.Slaves[i]
+		position := uint16(i + 1)
+		if int(position) > len(m.Slaves) || m.Slaves[position-1].PDO == nil {
+			return fmt.Errorf("slave %d is not mapped", position)
+		}
+		pdo := m.Slaves[position-1].PDO
+
+		for _, image := range []struct {
+			name   string
+			eni    *BitRange
+			base   Number
+			bits   uint16
+			offset uint
+		}{
+			{"output", s.ProcessData.Send, outputBase, pdo.OutputBits, pdo.OutputOffset},
+			{"input", s.ProcessData.Recv, inputBase, pdo.InputBits, pdo.InputOffset},
+		} {
+			if image.eni == nil {
+				continue
+			}
+			if uint(image.eni.BitLength) != uint(image.bits) {
+				return fmt.Errorf("slave %d %s has %d %s bits, ENI expects %d",
+					position, s.Info.Name, image.bits, image.name, image.eni.BitLength)
+			}
+			if image.bits > 0 && uint(image.eni.BitStart-image.base) != image.offset {
+				return fmt.Errorf("slave %d %s %ss are mapped at bit %d, ENI expects %d",
+					position, s.Info.Name, image.name, image.offset, image.eni.BitStart-image.base)
+			}
+		}
+
+		if err := m.SetVariables(position, s.variables(&c.ProcessImage)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lowest BitStart of the slaves' outputs and inputs
+func (c *Config) imageBases() (outputBase, inputBase Number) {
+	outputSet, inputSet := false, false
+	for _, s := range c.Slaves {
+		if r := s.ProcessData.Send; r != nil && r.BitLength > 0 && (!outputSet || r.BitStart < outputBase) {
+			outputBase, outputSet = r.BitStart, true
+		}
+		if r := s.ProcessData.Recv; r != nil && r.BitLength > 0 && (!inputSet || r.BitStart < inputBase) {
+			inputBase, inputSet = r.BitStart, true
+		}
+	}
+	return outputBase, inputBase
+}
+
+// Variables of the assigned PDOs. Where the process image lists variables
+// within the slave's outputs or inputs, those are used at their image
+// offsets and matched by offset to the PDO entries; otherwise that
+// direction's variables follow PDO order.
+func (s *Slave) variables(image *ProcessImage) []*soem.Variable {
+	pdoVars := bind.Variables(s.ProcessData.RxPDOs, s.ProcessData.TxPDOs)
+
+	var vars []*soem.Variable
+	for _, dir := range []struct {
+		image     *Image
+		bits      *BitRange
+		direction soem.PDODirection
+	}{
+		{&image.Outputs, s.ProcessData.Send, soem.RxPDO},
+		{&image.Inputs, s.ProcessData.Recv, soem.TxPDO},
+	} {
+		found := false
+		for _, iv := range dir.image.Variables {
+			if dir.bits == nil || iv.BitOffs < dir.bits.BitStart || iv.BitOffs+iv.BitSize > dir.bits.BitStart+dir.bits.BitLength {
+				continue
+			}
+
+			v := &soem.Variable{
+				Direction: dir.direction,
+				Name:      strings.TrimPrefix(iv.Name, s.Info.Name+"."),
+				BitOffset: uint(iv.BitOffs - dir.bits.BitStart),
+				BitLength: uint(iv.BitSize),
+			}
+			if t, ok := soem.ParseDataType(iv.DataType); ok {
+				v.DataType = t
+			} else {
+				v.DataType = soem.ECT_OCTET_STRING
+			}
+			for _, pv := range pdoVars {
+				if pv.Direction == v.Direction && pv.BitOffset == v.BitOffset && pv.BitLength == v.BitLength {
+					v.PDOIndex, v.PDOName = pv.PDOIndex, pv.PDOName
+					v.Index, v.SubIndex, v.Name = pv.Index, pv.SubIndex, pv.Name
+					break
+				}
+			}
+			vars = append(vars, v)
+			found = true
+		}
+
+		if !found {
+			for _, pv := range pdoVars {
+				if pv.Direction == dir.direction {
+					vars = append(vars, pv)
+				}
+			}
+		}
+	}
+
+	return vars
+}
@@ -0,0 +1,193 @@
+// Package eni reads EtherCAT Network Information files, as exported by
+// TwinCAT and other configurators, and applies them to a soem.Master.
+package eni
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/siyka-au/go-soem/esi"
+)
+
+// ENI files share their number and PDO elements with ESI files
+type (
+	Number = esi.Number
+	PDO    = esi.PDO
+	Entry  = esi.Entry
+)
+
+// Binary data written as a hex string
+type HexBinary []byte
+
+func (h *HexBinary) UnmarshalText(text []byte) error {
+	data, err := hex.DecodeString(strings.TrimSpace(string(text)))
+	if err != nil {
+		return fmt.Errorf("invalid hex data %q", text)
+	}
+	*h = data
+	return nil
+}
+
+// The contents of an ENI file
+type Config struct {
+	XMLName      xml.Name     `xml:"EtherCATConfig"`
+	Slaves       []Slave      `xml:"Config>Slave"`
+	ProcessImage ProcessImage `xml:"Config>ProcessImage"`
+}
+
+type Slave struct {
+	Info        SlaveInfo   `xml:"Info"`
+	ProcessData ProcessData `xml:"ProcessData"`
+	// Nil if the slave has no CoE mailbox
+	CoE      *CoE      `xml:"Mailbox>CoE"`
+	InitCmds []InitCmd `xml:"InitCmds>InitCmd"`
+	// Nil if distributed clocks are not used
+	DC *DC `xml:"DC"`
+}
+
+type SlaveInfo struct {
+	Name        string `xml:"Name"`
+	PhysAddr    Number `xml:"PhysAddr"`
+	AutoIncAddr Number `xml:"AutoIncAddr"`
+	VendorID    Number `xml:"VendorId"`
+	ProductCode Number `xml:"ProductCode"`
+	RevisionNo  Number `xml:"RevisionNo"`
+	SerialNo    Number `xml:"SerialNo"`
+}
+
+type ProcessData struct {
+	// Location of the slave's outputs in the output image
+	Send *BitRange `xml:"Send"`
+	// Location of the slave's inputs in the input image
+	Recv   *BitRange `xml:"Recv"`
+	RxPDOs []PDO     `xml:"RxPdo"`
+	TxPDOs []PDO     `xml:"TxPdo"`
+}
+
+type BitRange struct {
+	BitStart  Number `xml:"BitStart"`
+	BitLength Number `xml:"BitLength"`
+}
+
+type CoE struct {
+	InitCmds []CoEInitCmd `xml:"InitCmds>InitCmd"`
+}
+
+// CoE command codes
+const (
+	CCS_DOWNLOAD = 1
+	CCS_UPLOAD   = 2
+)
+
+// An SDO access made during state transitions
+type CoEInitCmd struct {
+	Fixed          bool      `xml:"Fixed,attr"`
+	CompleteAccess bool      `xml:"CompleteAccess,attr"`
+	Transitions    []string  `xml:"Transition"`
+	Comment        string    `xml:"Comment"`
+	Timeout        Number    `xml:"Timeout"`
+	Ccs            Number    `xml:"Ccs"`
+	Index          Number    `xml:"Index"`
+	SubIndex       Number    `xml:"SubIndex"`
+	Data           HexBinary `xml:"Data"`
+}
+
+// EtherCAT command types of register init commands
+const (
+	CMD_APRD = 1
+	CMD_APWR = 2
+	CMD_FPRD = 4
+	CMD_FPWR = 5
+	CMD_BRD  = 7
+	CMD_BWR  = 8
+)
+
+// A register access made during state transitions
+type InitCmd struct {
+	Transitions []string  `xml:"Transition"`
+	Comment     string    `xml:"Comment"`
+	Cmd         Number    `xml:"Cmd"`
+	Adp         Number    `xml:"Adp"`
+	Ado         Number    `xml:"Ado"`
+	Data        HexBinary `xml:"Data"`
+	Cnt         Number    `xml:"Cnt"`
+	Retries     Number    `xml:"Retries"`
+}
+
+// Distributed clock settings, times in nanoseconds
+type DC struct {
+	ReferenceClock bool   `xml:"ReferenceClock"`
+	CycleTime0     Number `xml:"CycleTime0"`
+	CycleTime1     Number `xml:"CycleTime1"`
+	ShiftTime      Number `xml:"ShiftTime"`
+}
+
+func (d *DC) Sync0Cycle() time.Duration {
+	return time.Duration(d.CycleTime0)
+}
+
+func (d *DC) Sync1Cycle() time.Duration {
+	return time.Duration(d.CycleTime1)
+}
+
+func (d *DC) Shift() time.Duration {
+	return time.Duration(int32(d.ShiftTime))
+}
+
+type ProcessImage struct {
+	Inputs  Image `xml:"Inputs"`
+	Outputs Image `xml:"Outputs"`
+}
+
+type Image struct {
+	ByteSize  Number     `xml:"ByteSize"`
+	Variables []Variable `xml:"Variable"`
+}
+
+// A variable of the process image, at a bit offset from the image start
+type Variable struct {
+	Name     string `xml:"Name"`
+	Comment  string `xml:"Comment"`
+	DataType string `xml:"DataType"`
+	BitSize  Number `xml:"BitSize"`
+	BitOffs  Number `xml:"BitOffs"`
+}
+
+// Whether the command runs on the transition, e.g. "PS" for PRE_OP to SAFE_OP
+func hasTransition(transitions []string, transition string) bool {
+	for _, t := range transitions {
+		if strings.EqualFold(strings.TrimSpace(t), transition) {
+			return true
+		}
+	}
+	return false
+}
+
+func Parse(r io.Reader) (*Config, error) {
+	cfg := new(Config)
+	d := xml.NewDecoder(r)
+	d.CharsetReader = esi.CharsetReader
+	if err := d.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("error parsing ENI: %w", err)
+	}
+	return cfg, nil
+}
+
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
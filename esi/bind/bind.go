@@ -0,0 +1,108 @@
+// Package bind applies ESI device descriptions to a soem.Master. It is kept
+// apart from esi so the parser and catalog do not depend on SOEM.
+package bind
+
+import (
+	"github.com/siyka-au/go-soem/esi"
+	"github.com/siyka-au/go-soem/soem"
+)
+
+// The PDOs the device assigns by default, for Master.SetPDOMapping
+func DefaultPDOMapping(d *esi.Device) soem.PDOMapping {
+	return PDOMapping(d.RxPDOs, d.TxPDOs)
+}
+
+// Variables of the PDOs the device assigns by default, offset from the
+// start of the slave's outputs or inputs in PDO order, for
+// Master.SetVariables
+func DeviceVariables(d *esi.Device) []*soem.Variable {
+	return Variables(d.RxPDOs, d.TxPDOs)
+}
+
+// The assignment of the assigned PDOs, with the entries of those that are
+// not fixed, for Master.SetPDOMapping
+func PDOMapping(rxPDOs, txPDOs []esi.PDO) soem.PDOMapping {
+	var mapping soem.PDOMapping
+	for _, pdos := range []struct {
+		pdos   []esi.PDO
+		target *[]soem.PDOConfig
+	}{{rxPDOs, &mapping.RxPDOs}, {txPDOs, &mapping.TxPDOs}} {
+		for _, pdo := range pdos.pdos {
+			if !pdo.Assigned() {
+				continue
+			}
+
+			config := soem.PDOConfig{Index: uint16(pdo.Index)}
+			if !pdo.Fixed {
+				for _, e := range pdo.Entries {
+					config.Entries = append(config.Entries, soem.PDOEntry{
+						Index:     uint16(e.Index),
+						SubIndex:  uint8(e.SubIndex),
+						BitLength: uint8(e.BitLen),
+					})
+				}
+			}
+			*pdos.target = append(*pdos.target, config)
+		}
+	}
+	return mapping
+}
+
+// Variables of the assigned PDOs, offset from the start of the slave's
+// outputs or inputs in PDO order. Padding entries are skipped.
+func Variables(rxPDOs, txPDOs []esi.PDO) []*soem.Variable {
+	var vars []*soem.Variable
+	for _, pdos := range []struct {
+		pdos      []esi.PDO
+		direction soem.PDODirection
+	}{{rxPDOs, soem.RxPDO}, {txPDOs, soem.TxPDO}} {
+		bitOffset := uint(0)
+		for _, pdo := range pdos.pdos {
+			if !pdo.Assigned() {
+				continue
+			}
+
+			for _, e := range pdo.Entries {
+				if e.Index != 0 {
+					vars = append(vars, &soem.Variable{
+						Direction: pdos.direction,
+						PDOIndex:  uint16(pdo.Index),
+						PDOName:   pdo.Name,
+						Index:     uint16(e.Index),
+						SubIndex:  uint8(e.SubIndex),
+						Name:      e.Name,
+						DataType:  e.Type(),
+						BitOffset: bitOffset,
+						BitLength: uint(e.BitLen),
+					})
+				}
+				bitOffset += uint(e.BitLen)
+			}
+		}
+	}
+	return vars
+}
+
+// Finds the description of a slave discovered by ConfigInit
+func Match(c *esi.Catalog, s *soem.Slave) *esi.Device {
+	return c.Lookup(s.VendorID, s.ProductCode, s.Revision)
+}
+
+// Binds the default PDO variables of each mapped slave found in the catalog,
+// skipping slaves with variables already. Must be called after ConfigMap.
+func SetVariables(m *soem.Master, c *esi.Catalog) error {
+	for _, s := range m.Slaves {
+		if s.PDO == nil || len(s.PDO.Variables) > 0 {
+			continue
+		}
+
+		d := Match(c, s)
+		if d == nil {
+			continue
+		}
+		if err := m.SetVariables(s.Position, DeviceVariables(d)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package esi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Device descriptions from any number of ESI files
+type Catalog struct {
+	Devices []*Device
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{}
+}
+
+func (c *Catalog) Add(info *EtherCATInfo) {
+	c.Devices = append(c.Devices, info.Devices...)
+}
+
+func (c *Catalog) LoadFile(path string) error {
+	info, err := Load(path)
+	if err != nil {
+		return err
+	}
+	c.Add(info)
+	return nil
+}
+
+// Loads every .xml file in dir
+func (c *Catalog) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".xml") {
+			continue
+		}
+		if err := c.LoadFile(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finds the description of a device. An exact revision is preferred,
+// otherwise the highest revision below it, otherwise the lowest above it.
+// Returns nil if the catalog has no device with the product code.
+func (c *Catalog) Lookup(vendorID, productCode, revision uint32) *Device {
+	var below, above *Device
+	for _, d := range c.Devices {
+		if uint32(d.Vendor.ID) != vendorID || uint32(d.Type.ProductCode) != productCode {
+			continue
+		}
+
+		rev := uint32(d.Type.RevisionNo)
+		switch {
+		case rev == revision:
+			return d
+		case rev < revision:
+			if below == nil || rev > uint32(below.Type.RevisionNo) {
+				below = d
+			}
+		default:
+			if above == nil || rev < uint32(above.Type.RevisionNo) {
+				above = d
+			}
+		}
+	}
+
+	if below != nil {
+		return below
+	}
+	return above
+}
@@ -0,0 +1,42 @@
+package esi
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Characters windows-1252 places at 0x80-0x9F, where ISO-8859-1 has C1
+// controls. The five unassigned codes map to the controls, as browsers do.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// Decodes the single-byte charsets vendors and configurators commonly write
+// ESI and ENI files in, for xml.Decoder.CharsetReader
+func CharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	var win bool
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1":
+	case "windows-1252", "cp1252":
+		win = true
+	default:
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+		if win && b >= 0x80 && b < 0xA0 {
+			runes[i] = windows1252[b-0x80]
+		}
+	}
+	return strings.NewReader(string(runes)), nil
+}
@@ -0,0 +1,223 @@
+// Package esi reads EtherCAT Slave Information device descriptions as
+// published by slave vendors.
+package esi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/siyka-au/go-soem/ectype"
+)
+
+// A number in ESI and ENI files, written either in decimal or in hex as
+// #x1A00
+type Number uint32
+
+func (n *Number) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	base := 10
+	if strings.HasPrefix(s, "#x") || strings.HasPrefix(s, "#X") {
+		s = s[2:]
+		base = 16
+	}
+
+	v, err := strconv.ParseInt(s, base, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", text)
+	}
+	*n = Number(v)
+	return nil
+}
+
+// The contents of an ESI file
+type EtherCATInfo struct {
+	XMLName xml.Name  `xml:"EtherCATInfo"`
+	Vendor  Vendor    `xml:"Vendor"`
+	Devices []*Device `xml:"Descriptions>Devices>Device"`
+}
+
+type Vendor struct {
+	ID   Number `xml:"Id"`
+	Name string `xml:"Name"`
+}
+
+type Device struct {
+	// Set from the file's vendor when the device is added to a catalog
+	Vendor Vendor `xml:"-"`
+
+	Type      DeviceType      `xml:"Type"`
+	Names     []LocalizedName `xml:"Name"`
+	GroupType string          `xml:"GroupType"`
+	// Use of each FMMU, e.g. Outputs, Inputs or MBoxState
+	FMMUs   []string      `xml:"Fmmu"`
+	SMs     []SyncManager `xml:"Sm"`
+	RxPDOs  []PDO         `xml:"RxPdo"`
+	TxPDOs  []PDO         `xml:"TxPdo"`
+	DCModes []DCMode      `xml:"Dc>OpMode"`
+	Mailbox *Mailbox      `xml:"Mailbox"`
+}
+
+type DeviceType struct {
+	ProductCode Number `xml:"ProductCode,attr"`
+	RevisionNo  Number `xml:"RevisionNo,attr"`
+	Name        string `xml:",chardata"`
+}
+
+type LocalizedName struct {
+	LcID Number `xml:"LcId,attr"`
+	Name string `xml:",chardata"`
+}
+
+// English name of the device, or the first name given
+func (d *Device) Name() string {
+	for _, n := range d.Names {
+		if n.LcID == 1033 {
+			return n.Name
+		}
+	}
+	if len(d.Names) > 0 {
+		return d.Names[0].Name
+	}
+	return d.Type.Name
+}
+
+func (d *Device) String() string {
+	return fmt.Sprintf("%s (0x%08x:0x%08x rev 0x%08x) %s",
+		d.Type.Name, d.Vendor.ID, d.Type.ProductCode, d.Type.RevisionNo, d.Name())
+}
+
+// Default sync manager configuration
+type SyncManager struct {
+	StartAddress Number `xml:"StartAddress,attr"`
+	ControlByte  Number `xml:"ControlByte,attr"`
+	DefaultSize  Number `xml:"DefaultSize,attr"`
+	MinSize      Number `xml:"MinSize,attr"`
+	MaxSize      Number `xml:"MaxSize,attr"`
+	Enable       bool   `xml:"Enable,attr"`
+	// MBoxOut, MBoxIn, Outputs or Inputs
+	Type string `xml:",chardata"`
+}
+
+type PDO struct {
+	Fixed     bool `xml:"Fixed,attr"`
+	Mandatory bool `xml:"Mandatory,attr"`
+	// Sync manager the PDO is assigned to by default, empty if it is not
+	SM      string  `xml:"Sm,attr"`
+	Index   Number  `xml:"Index"`
+	Name    string  `xml:"Name"`
+	Entries []Entry `xml:"Entry"`
+	// PDOs that cannot be assigned together with this one
+	Excludes []Number `xml:"Exclude"`
+}
+
+// Whether the PDO is assigned by default
+func (p *PDO) Assigned() bool {
+	return p.SM != ""
+}
+
+type Entry struct {
+	Index    Number `xml:"Index"`
+	SubIndex Number `xml:"SubIndex"`
+	BitLen   Number `xml:"BitLen"`
+	Name     string `xml:"Name"`
+	DataType string `xml:"DataType"`
+}
+
+// The entry's data type, octet string if it is not recognised
+func (e *Entry) Type() ectype.DataType {
+	if t, ok := ectype.ParseDataType(e.DataType); ok {
+		return t
+	}
+	return ectype.ECT_OCTET_STRING
+}
+
+// A distributed clock operation mode, times in nanoseconds
+type DCMode struct {
+	Name           string `xml:"Name"`
+	Desc           string `xml:"Desc"`
+	AssignActivate Number `xml:"AssignActivate"`
+	CycleTimeSync0 Number `xml:"CycleTimeSync0"`
+	ShiftTimeSync0 Number `xml:"ShiftTimeSync0"`
+	CycleTimeSync1 Number `xml:"CycleTimeSync1"`
+	ShiftTimeSync1 Number `xml:"ShiftTimeSync1"`
+}
+
+// Whether the mode uses SYNC0, from bit 9 of AssignActivate (register 0x981)
+func (m *DCMode) UsesSync0() bool {
+	return m.AssignActivate&0x200 != 0
+}
+
+// Whether the mode uses SYNC1, from bit 10 of AssignActivate
+func (m *DCMode) UsesSync1() bool {
+	return m.AssignActivate&0x400 != 0
+}
+
+func (m *DCMode) Shift() time.Duration {
+	return time.Duration(int32(m.ShiftTimeSync0))
+}
+
+// Mailbox protocols the device supports, nil when the element is missing
+type Mailbox struct {
+	AoE *struct{} `xml:"AoE"`
+	EoE *struct{} `xml:"EoE"`
+	CoE *struct{} `xml:"CoE"`
+	FoE *struct{} `xml:"FoE"`
+	SoE *struct{} `xml:"SoE"`
+	VoE *struct{} `xml:"VoE"`
+}
+
+func (m *Mailbox) Protocols() ectype.MailboxProtocol {
+	var p ectype.MailboxProtocol
+	if m == nil {
+		return p
+	}
+	for _, proto := range []struct {
+		present bool
+		flag    ectype.MailboxProtocol
+	}{
+		{m.AoE != nil, ectype.ECT_MBXPROT_AOE},
+		{m.EoE != nil, ectype.ECT_MBXPROT_EOE},
+		{m.CoE != nil, ectype.ECT_MBXPROT_COE},
+		{m.FoE != nil, ectype.ECT_MBXPROT_FOE},
+		{m.SoE != nil, ectype.ECT_MBXPROT_SOE},
+		{m.VoE != nil, ectype.ECT_MBXPROT_VOE},
+	} {
+		if proto.present {
+			p |= proto.flag
+		}
+	}
+	return p
+}
+
+func Parse(r io.Reader) (*EtherCATInfo, error) {
+	info := new(EtherCATInfo)
+	d := xml.NewDecoder(r)
+	d.CharsetReader = CharsetReader
+	if err := d.Decode(info); err != nil {
+		return nil, fmt.Errorf("error parsing ESI: %w", err)
+	}
+
+	for _, dev := range info.Devices {
+		dev.Vendor = info.Vendor
+	}
+	return info, nil
+}
+
+func Load(path string) (*EtherCATInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return info, nil
+}
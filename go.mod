@@ -2,4 +2,4 @@ module github.com/siyka-au/go-soem
 
 go 1.17
 
-require github.com/qmuntal/stateless v1.5.2 // indirect
+require github.com/qmuntal/stateless v1.5.2
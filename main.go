@@ -42,7 +42,13 @@ func main() {
 
 func run(ctx context.Context, args []string) error {
 
-	master, err := soem.NewSOEMMaster(args[1])
+	var master *soem.Master
+	var err error
+	if len(args) > 2 {
+		master, err = soem.NewRedundantMaster(args[1], args[2])
+	} else {
+		master, err = soem.NewSOEMMaster(args[1])
+	}
 	if err != nil {
 		return err
 	}
@@ -50,12 +56,29 @@ func run(ctx context.Context, args []string) error {
 
 	master.ConfigInit()
 	fmt.Printf("Found %d attached slaves\n", master.SlaveCount)
+	fmt.Print(master.Topology())
+
+	master.SetExpectedNetwork(&soem.NetworkConfig{
+		Slaves: []soem.ExpectedSlave{
+			{Name: "EK1100", VendorID: 0x2, ProductCode: 0x044c2c52},
+			{Name: "EL1008", VendorID: 0x2, ProductCode: 0x03f03052},
+			{Name: "EL1004", VendorID: 0x2, ProductCode: 0x03ec3052},
+			{Name: "EL2008", VendorID: 0x2, ProductCode: 0x07d83052},
+			{Name: "EL2004", VendorID: 0x2, ProductCode: 0x07d43052},
+		},
+	})
+
+	master.OnEmergency(0, func(e *soem.Emergency) {
+		fmt.Println(e)
+	})
 
 	// if master.ConfigDC() && master.Slaves[2].HasDC {
 	// 	master.DCSync0(2, 200*time.Millisecond, 0)
 	// }
 
-	master.ConfigMap(1024)
+	if err := master.ConfigMap(1024); err != nil {
+		return err
+	}
 
 	if err := stateCheck(master, soem.EC_STATE_SAFE_OP); err != nil {
 		fmt.Println(err)
@@ -78,88 +101,107 @@ func run(ctx context.Context, args []string) error {
 		fmt.Println(err)
 	}
 
-	// ctrl := NewController()
-
-	ticker := time.NewTicker(50 * time.Millisecond)
+	wkcEvents := make(chan soem.WKCEvent, 8)
+	if err := master.SetWKCPolicy(0, soem.WKCPolicy{Threshold: 3, Events: wkcEvents}); err != nil {
+		return err
+	}
 
+	supervisor, err := soem.NewSupervisor(master, 0)
+	if err != nil {
+		return err
+	}
+	go supervisor.Run(ctx)
 	go func() {
-		const light0Max uint8 = 1 << 7
-		const light1Max uint8 = 1 << 3
-		light0DirUp := true
-		light1DirUp := true
-		lights0 := uint8(1)
-		lights1 := uint8(1)
-
-		calcDir := func(dir bool, max, min, val uint8) bool {
-			return (!(val == max) && (val == min)) || (dir && !(val == max))
+		for {
+			select {
+			case e := <-supervisor.Events:
+				fmt.Println(e)
+			case e := <-wkcEvents:
+				fmt.Println(e)
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
 
-		stepLight := func(dir bool, val uint8) uint8 {
-			if dir {
-				return val << 1
-			} else {
-				return val >> 1
-			}
+	// ctrl := NewController()
+
+	cycle := soem.NewCycle(master, 0, 50*time.Millisecond)
+	cycle.OnOverrun = func(late time.Duration) {
+		fmt.Printf("Cycle overrun by %s\n", late)
+	}
+
+	const light0Max uint8 = 1 << 7
+	const light1Max uint8 = 1 << 3
+	light0DirUp := true
+	light1DirUp := true
+	lights0 := uint8(1)
+	lights1 := uint8(1)
+
+	calcDir := func(dir bool, max, min, val uint8) bool {
+		return (!(val == max) && (val == min)) || (dir && !(val == max))
+	}
+
+	stepLight := func(dir bool, val uint8) uint8 {
+		if dir {
+			return val << 1
+		} else {
+			return val >> 1
 		}
+	}
 
-		// ctrl := controller.NewController()
-		// am := plc.NewAutoManual()
-		mc := plc.NewMultiClick(2, 500*time.Millisecond)
+	// ctrl := controller.NewController()
+	// am := plc.NewAutoManual()
+	mc := plc.NewMultiClick(2, 500*time.Millisecond)
 
-		// startTrig := plc.NewRisingEdge()
-		// cancelTrig := plc.NewRisingEdge()
-		multiClickTrig := plc.NewRisingEdge()
+	// startTrig := plc.NewRisingEdge()
+	// cancelTrig := plc.NewRisingEdge()
+	multiClickTrig := plc.NewRisingEdge()
 
-		/*
-		 * Main PDO loop
-		 * Print inputs as binary strings
-		 * Bit shift a bit through the output range
-		 */
-		for {
-			select {
-			case <-ticker.C:
-				// fmt.Println("Processing I/O")
-				master.SendProcessData()
-				master.ReceiveProcessData(soem.EC_TIMEOUTRET)
+	/*
+	 * Main PDO loop
+	 * Print inputs as binary strings
+	 * Bit shift a bit through the output range
+	 */
+	cycle.Add(func(_ soem.CycleInfo) {
+		master.DrainErrors()
 
-				el1008 := master.Slaves[1].Read()[0]
-				// el1004 := master.Slaves[2].Read()[0]
-				// fmt.Printf("Inputs: %08b %08b\n", el1008, el1004)
+		el1008 := master.Slaves[1].Inputs()
+		// el1004 := master.Slaves[2].Inputs()
+		// fmt.Printf("Inputs: %08b %08b\n", master.Slaves[1].Read()[0], master.Slaves[2].Read()[0])
 
-				// if startTrig.Run(el1008&0x01 != 0) {
-				// 	am.StartManual(10 * time.Second)
-				// }
+		// if start, _ := el1008.Bool(0); startTrig.Run(start) {
+		// 	am.StartManual(10 * time.Second)
+		// }
 
-				// if cancelTrig.Run(el1008&0x02 != 0) {
-				// 	am.CancelManual()
-				// }
+		// if cancel, _ := el1008.Bool(1); cancelTrig.Run(cancel) {
+		// 	am.CancelManual()
+		// }
 
-				if multiClickTrig.Run(el1008&0x04 != 0) {
-					mc.Click()
-				}
+		if click, _ := el1008.Bool(2); multiClickTrig.Run(click) {
+			mc.Click()
+		}
 
-				light0DirUp = calcDir(light0DirUp, light0Max, 1, lights0)
-				light1DirUp = calcDir(light1DirUp, light1Max, 1, lights1)
+		light0DirUp = calcDir(light0DirUp, light0Max, 1, lights0)
+		light1DirUp = calcDir(light1DirUp, light1Max, 1, lights1)
 
-				lights0 = stepLight(light0DirUp, lights0)
-				lights1 = stepLight(light1DirUp, lights1)
+		lights0 = stepLight(light0DirUp, lights0)
+		lights1 = stepLight(light1DirUp, lights1)
 
-				master.Slaves[3].Write([]byte{lights0})
-				master.Slaves[4].Write([]byte{lights1})
+		master.Slaves[3].Outputs().SetUint8(0, lights0)
+		master.Slaves[4].Outputs().SetBitfield(0, 4, uint64(lights1))
 
-				select {
-				case <-mc.Clicks:
-					fmt.Println("Clicked three times!")
-				default:
-				}
-			case <-ctx.Done():
-				ticker.Stop()
-				return
-			}
+		select {
+		case <-mc.Clicks:
+			fmt.Println("Clicked three times!")
+		default:
 		}
-	}()
+	})
 
-	<-ctx.Done()
+	if err := cycle.Run(ctx); err != nil {
+		return err
+	}
+	fmt.Println(cycle.Stats())
 	if _, err := master.SetState(soem.EC_STATE_INIT); err != nil {
 		return err
 	}
@@ -0,0 +1,157 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+*/
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// CRC-8 of the first 14 bytes of the SII, stored in the low byte of word 7.
+// Slaves refuse to load an SII whose checksum does not match.
+func SIIChecksum(data []byte) uint8 {
+	crc := uint8(0xFF)
+	for _, b := range data[:EC_SII_CHECKSUM*2] {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Programs the configured station alias of slave into its SII, updating the
+// checksum, and reloads the EEPROM so the alias takes effect immediately
+func (m *Master) SetAlias(slave uint16, alias uint16) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+	defer m.invalidateSII(slave)
+
+	var config [(EC_SII_CHECKSUM + 1) * 2]byte
+	for word := 0; word < len(config)/2; word += 2 {
+		v, err := m.readEEPROMWords(slave, uint16(word))
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint32(config[word*2:], v)
+	}
+
+	binary.LittleEndian.PutUint16(config[EC_SII_ALIAS*2:], alias)
+	config[EC_SII_CHECKSUM*2] = SIIChecksum(config[:])
+
+	if err := m.writeEEPROMWord(slave, EC_SII_ALIAS, alias); err != nil {
+		return err
+	}
+	if err := m.writeEEPROMWord(slave, EC_SII_CHECKSUM, binary.LittleEndian.Uint16(config[EC_SII_CHECKSUM*2:])); err != nil {
+		return err
+	}
+
+	if err := m.ReloadEEPROM(slave); err != nil {
+		return err
+	}
+
+	var buf [2]byte
+	if err := m.readRegister(slave, C.ECT_REG_ALIAS, buf[:]); err != nil {
+		return err
+	}
+	if loaded := binary.LittleEndian.Uint16(buf[:]); loaded != alias {
+		return fmt.Errorf("slave %d loaded alias %d after writing %d", slave, loaded, alias)
+	}
+
+	m.ecSlave(slave).aliasadr = C.uint16(alias)
+	m.Slaves[slave-1].AliasAddress = alias
+	return nil
+}
+
+// EEPROM control/status register (0x0502) bits as the ESC defines them.
+// Commands are in bits 8-10, where reload is 100b; SOEM's EC_ECMD_RELOAD
+// (0x0300) is not a valid ESC command.
+const (
+	escEEPROMReload    = 0x0400
+	escEEPROMChecksum  = 0x0800
+	escEEPROMNotLoaded = 0x1000
+	escEEPROMNack      = 0x2000
+	escEEPROMWriteErr  = 0x4000
+)
+
+// Makes the slave reload its configuration from the SII, as it does on
+// power up, and waits for the reload to finish
+func (m *Master) ReloadEEPROM(slave uint16) error {
+	if err := m.EEPROMToMaster(slave); err != nil {
+		return err
+	}
+
+	var cmd [2]byte
+	binary.LittleEndian.PutUint16(cmd[:], escEEPROMReload)
+	if err := m.WriteRegister(slave, C.ECT_REG_EEPCTL, cmd[:]); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(EC_TIMEOUTEEP * time.Microsecond)
+	var status uint16
+	for {
+		var buf [2]byte
+		if err := m.readRegister(slave, C.ECT_REG_EEPSTAT, buf[:]); err != nil {
+			return err
+		}
+		status = binary.LittleEndian.Uint16(buf[:])
+		if status&C.EC_ESTAT_BUSY == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout reloading EEPROM of slave %d", slave)
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	m.EEPROMToPDI(slave)
+
+	switch {
+	case status&escEEPROMChecksum != 0:
+		return fmt.Errorf("EEPROM of slave %d has an invalid checksum", slave)
+	case status&escEEPROMNack != 0:
+		return fmt.Errorf("EEPROM of slave %d did not acknowledge the reload", slave)
+	case status&escEEPROMWriteErr != 0:
+		return fmt.Errorf("EEPROM command error on slave %d", slave)
+	case status&escEEPROMNotLoaded != 0:
+		return fmt.Errorf("EEPROM of slave %d was not loaded", slave)
+	}
+	return nil
+}
+
+// Finds the slave with the configured station alias. Aliases let a slave be
+// addressed independently of its position, so a replacement module
+// programmed with the same alias takes over its predecessor's role.
+func (m *Master) SlaveByAlias(alias uint16) (*Slave, error) {
+	if alias == 0 {
+		return nil, fmt.Errorf("alias 0 is not a valid alias")
+	}
+
+	var found *Slave
+	for _, s := range m.Slaves {
+		if s.AliasAddress != alias {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("alias %d is used by slaves %d and %d", alias, found.Position, s.Position)
+		}
+		found = s
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no slave with alias %d", alias)
+	}
+	return found, nil
+}
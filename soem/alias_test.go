@@ -0,0 +1,34 @@
+package soem
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestSIIChecksum(t *testing.T) {
+	withAlias := func(alias uint16) []byte {
+		data := append([]byte(nil), siiConfigArea...)
+		binary.LittleEndian.PutUint16(data[EC_SII_ALIAS*2:], alias)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want uint8
+	}{
+		{"LAN9252 SPI configuration", siiConfigArea, 0x6c},
+		{"alias 1001", withAlias(1001), 0x0d},
+		{"blank", make([]byte, 14), 0x30},
+		{"erased", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0xa3},
+		{"check string", []byte("123456789ABCDE"), 0x43},
+		// the checksum word and anything after it are not covered
+		{"stored checksum", append(append([]byte(nil), siiConfigArea...), 0x6c, 0x00, 0x02, 0x00), 0x6c},
+	}
+
+	for _, tt := range tests {
+		if got := SIIChecksum(tt.data); got != tt.want {
+			t.Errorf("%s: SIIChecksum(% x) = 0x%02x, want 0x%02x", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
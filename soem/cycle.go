@@ -0,0 +1,166 @@
+package soem
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+type CycleInfo struct {
+	// Cycles since Run started
+	Count uint64
+	// Working counter of the exchange that just completed
+	WKC int
+	// Monotonic deadline the cycle was woken for, in ns
+	Deadline int64
+}
+
+type CycleFunc func(info CycleInfo)
+
+// Cycle exchanges the process data of a group on a fixed period from a
+// dedicated OS thread. Each cycle sleeps until an absolute deadline,
+// receives the frame sent in the previous cycle, runs the registered
+// callbacks and sends the next frame.
+type Cycle struct {
+	// accessed atomically, kept first for 64-bit alignment on 32-bit platforms
+	overruns uint64
+	cycles   uint64
+
+	latency   Histogram
+	jitter    Histogram
+	roundTrip Histogram
+	execution Histogram
+
+	master *Master
+	group  uint8
+	period time.Duration
+
+	// SCHED_FIFO priority of the cycle thread, 0 leaves the scheduler alone
+	Priority int
+	// CPU to pin the cycle thread to, -1 for no affinity
+	CPU int
+	// Receive timeout in us
+	Timeout int
+	// Aligns the cycle to the DC reference clock when set
+	DCSync *DCSyncController
+	// Called from the cycle thread with how late a cycle woke, when it woke
+	// after its successor's deadline. The missed cycles are skipped.
+	OnOverrun func(late time.Duration)
+
+	callbacks []CycleFunc
+}
+
+func NewCycle(master *Master, group uint8, period time.Duration) *Cycle {
+	return &Cycle{
+		master:  master,
+		group:   group,
+		period:  period,
+		CPU:     -1,
+		Timeout: EC_TIMEOUTRET,
+	}
+}
+
+// Registers fn to run every cycle between receive and send. Callbacks run
+// in the order added and must not be added while the cycle is running.
+func (c *Cycle) Add(fn CycleFunc) {
+	c.callbacks = append(c.callbacks, fn)
+}
+
+func (c *Cycle) Overruns() uint64 {
+	return atomic.LoadUint64(&c.overruns)
+}
+
+// Returns a snapshot of the cycle timing. Safe to call while running.
+func (c *Cycle) Stats() CycleStats {
+	wkc, _ := c.master.WKCStatus(c.group)
+	return CycleStats{
+		Cycles:    atomic.LoadUint64(&c.cycles),
+		Overruns:  atomic.LoadUint64(&c.overruns),
+		WKCErrors: wkc.Misses,
+		LastWKC:   wkc.Last,
+		Latency:   c.latency.Snapshot(),
+		Jitter:    c.jitter.Snapshot(),
+		RoundTrip: c.roundTrip.Snapshot(),
+		Execution: c.execution.Snapshot(),
+	}
+}
+
+// Runs the cycle until ctx is cancelled
+func (c *Cycle) Run(ctx context.Context) error {
+	if c.period <= 0 {
+		return fmt.Errorf("invalid cycle period %s", c.period)
+	}
+	if err := checkGroup(c.group); err != nil {
+		return err
+	}
+
+	runtime.LockOSThread()
+	// a thread with changed scheduling is left locked so the runtime
+	// discards it when Run returns instead of reusing it
+	if c.Priority == 0 && c.CPU < 0 {
+		defer runtime.UnlockOSThread()
+	}
+
+	if c.CPU >= 0 {
+		if err := setAffinity(c.CPU); err != nil {
+			return fmt.Errorf("error pinning cycle to CPU %d: %w", c.CPU, err)
+		}
+	}
+	if c.Priority > 0 {
+		if err := setFIFO(c.Priority); err != nil {
+			return fmt.Errorf("error setting SCHED_FIFO priority %d: %w", c.Priority, err)
+		}
+	}
+
+	m := c.master
+	period := c.period.Nanoseconds()
+	info := CycleInfo{}
+
+	m.sendProcessData(c.group)
+	deadline := monotonicNow()
+	lastWake := int64(0)
+
+	for {
+		deadline += period
+		if c.DCSync != nil {
+			deadline += c.DCSync.Correction(m.DCTime()).Nanoseconds()
+		}
+		sleepUntil(deadline)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		wake := monotonicNow()
+		c.latency.Record(time.Duration(wake - deadline))
+		if lastWake != 0 {
+			c.jitter.Record(time.Duration(wake - lastWake - period))
+		}
+		lastWake = wake
+
+		if late := wake - deadline; late > period {
+			atomic.AddUint64(&c.overruns, 1)
+			if c.OnOverrun != nil {
+				c.OnOverrun(time.Duration(late))
+			}
+			deadline += late / period * period
+		}
+
+		info.WKC = int(m.receiveProcessData(c.group, c.Timeout))
+		c.roundTrip.Record(time.Duration(monotonicNow() - wake))
+
+		info.Deadline = deadline
+		for _, fn := range c.callbacks {
+			fn(info)
+		}
+		m.sendProcessData(c.group)
+		info.Count++
+		atomic.AddUint64(&c.cycles, 1)
+
+		c.execution.Record(time.Duration(monotonicNow() - wake))
+	}
+}
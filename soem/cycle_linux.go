@@ -0,0 +1,70 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#define _GNU_SOURCE
+#include <errno.h>
+#include <pthread.h>
+#include <sched.h>
+#include <stdint.h>
+#include <time.h>
+
+static int64_t soem_monotonic_now(void) {
+	struct timespec ts;
+	clock_gettime(CLOCK_MONOTONIC, &ts);
+	return (int64_t)ts.tv_sec * 1000000000LL + ts.tv_nsec;
+}
+
+static int soem_sleep_until(int64_t ns) {
+	struct timespec ts;
+	int ret;
+
+	ts.tv_sec = ns / 1000000000LL;
+	ts.tv_nsec = ns % 1000000000LL;
+	do {
+		ret = clock_nanosleep(CLOCK_MONOTONIC, TIMER_ABSTIME, &ts, NULL);
+	} while (ret == EINTR);
+	return ret;
+}
+
+static int soem_set_fifo(int priority) {
+	struct sched_param param;
+	param.sched_priority = priority;
+	return pthread_setschedparam(pthread_self(), SCHED_FIFO, &param);
+}
+
+static int soem_set_affinity(int cpu) {
+	cpu_set_t set;
+	CPU_ZERO(&set);
+	CPU_SET(cpu, &set);
+	return pthread_setaffinity_np(pthread_self(), sizeof(set), &set);
+}
+
+*/
+import "C"
+import "syscall"
+
+// CLOCK_MONOTONIC in ns
+func monotonicNow() int64 {
+	return int64(C.soem_monotonic_now())
+}
+
+// Sleeps until the absolute monotonic time ns
+func sleepUntil(ns int64) {
+	C.soem_sleep_until(C.int64_t(ns))
+}
+
+func setFIFO(priority int) error {
+	if ret := C.soem_set_fifo(C.int(priority)); ret != 0 {
+		return syscall.Errno(ret)
+	}
+	return nil
+}
+
+func setAffinity(cpu int) error {
+	if ret := C.soem_set_affinity(C.int(cpu)); ret != 0 {
+		return syscall.Errno(ret)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build !linux
+// +build !linux
+
+package soem
+
+import (
+	"errors"
+	"time"
+)
+
+var monotonicStart = time.Now()
+
+// Go's monotonic clock in ns
+func monotonicNow() int64 {
+	return int64(time.Since(monotonicStart))
+}
+
+// Sleeps until the absolute monotonic time ns. Less precise than
+// clock_nanosleep, as it relies on the Go timer.
+func sleepUntil(ns int64) {
+	time.Sleep(time.Duration(ns - monotonicNow()))
+}
+
+func setFIFO(priority int) error {
+	return errors.New("SCHED_FIFO is only supported on Linux")
+}
+
+func setAffinity(cpu int) error {
+	return errors.New("CPU affinity is only supported on Linux")
+}
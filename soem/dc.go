@@ -0,0 +1,163 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+*/
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// DC system time starts at 2000-01-01 00:00:00 UTC
+var DCEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Reference clock time in ns since DCEpoch, as returned with the last
+// process data exchange of a group with DC slaves. Safe to call while
+// another goroutine exchanges process data.
+func (m *Master) DCTime() int64 {
+	return atomic.LoadInt64(&m.dcTime)
+}
+
+// Reads the system time of the slave's local clock directly
+func (m *Master) ReadDCSystemTime(slave uint16) (int64, error) {
+	var buf [8]byte
+	if err := m.readRegister(slave, C.ECT_REG_DCSYSTIME, buf[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(buf[:])), nil
+}
+
+// Reads the offset between the slave's local time and the system time
+func (m *Master) ReadDCSystemTimeOffset(slave uint16) (time.Duration, error) {
+	var buf [8]byte
+	if err := m.readRegister(slave, C.ECT_REG_DCSYSOFFSET, buf[:]); err != nil {
+		return 0, err
+	}
+	return time.Duration(int64(binary.LittleEndian.Uint64(buf[:]))), nil
+}
+
+// Reads the slave's mean deviation from the reference clock, a measure of how
+// well the slave's clock is synchronised
+func (m *Master) ReadDCSystemTimeDifference(slave uint16) (time.Duration, error) {
+	var buf [4]byte
+	if err := m.readRegister(slave, C.ECT_REG_DCSYSDIFF, buf[:]); err != nil {
+		return 0, err
+	}
+
+	// sign and magnitude, bit 31 set when the local copy is smaller than
+	// the received system time
+	v := binary.LittleEndian.Uint32(buf[:])
+	diff := time.Duration(v & 0x7fffffff)
+	if v&0x80000000 != 0 {
+		diff = -diff
+	}
+	return diff, nil
+}
+
+func (m *Master) readRegister(slave uint16, register uint16, buf []byte) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+
+	wkc := C.ecx_FPRD(m.context.port,
+		m.ecSlave(slave).configadr,
+		C.uint16(register),
+		C.uint16(len(buf)),
+		unsafe.Pointer(&buf[0]),
+		C.int(EC_TIMEOUTRET))
+	if wkc <= 0 {
+		return fmt.Errorf("error reading register 0x%04x of slave %d", register, slave)
+	}
+	return nil
+}
+
+// Writes data to an ESC register of slave using its configured address
+func (m *Master) WriteRegister(slave uint16, register uint16, data []byte) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	wkc := C.ecx_FPWR(m.context.port,
+		m.ecSlave(slave).configadr,
+		C.uint16(register),
+		C.uint16(len(data)),
+		unsafe.Pointer(&data[0]),
+		C.int(EC_TIMEOUTRET))
+	if wkc <= 0 {
+		return fmt.Errorf("error writing register 0x%04x of slave %d", register, slave)
+	}
+	return nil
+}
+
+// DCSyncController aligns the master's cycle to the DC reference clock with
+// a PI controller, as in the SOEM examples. Feed it the DC time after each
+// exchange and add the returned correction to the next cycle's wake time.
+type DCSyncController struct {
+	CycleTime time.Duration
+	// Target distance of the master's cycle after the SYNC0 event
+	SyncOffset time.Duration
+	// Proportional gain divisor, correction is -delta/Kp. 0 disables the
+	// proportional term.
+	Kp int64
+	// Integral gain divisor, correction is -integral/Ki. 0 disables the
+	// integral term.
+	Ki int64
+
+	integral int64
+	// Last measured deviation from the target, for jitter monitoring
+	Delta time.Duration
+}
+
+func NewDCSyncController(cycleTime, syncOffset time.Duration) *DCSyncController {
+	return &DCSyncController{
+		CycleTime:  cycleTime,
+		SyncOffset: syncOffset,
+		Kp:         100,
+		Ki:         20,
+	}
+}
+
+func (c *DCSyncController) Correction(dcTime int64) time.Duration {
+	cycle := c.CycleTime.Nanoseconds()
+	if cycle <= 0 {
+		return 0
+	}
+
+	delta := (dcTime - c.SyncOffset.Nanoseconds()) % cycle
+	if delta > cycle/2 {
+		delta -= cycle
+	}
+	if delta > 0 {
+		c.integral++
+	}
+	if delta < 0 {
+		c.integral--
+	}
+	c.Delta = time.Duration(delta)
+
+	var correction int64
+	if c.Kp != 0 {
+		correction -= delta / c.Kp
+	}
+	if c.Ki != 0 {
+		correction -= c.integral / c.Ki
+	}
+	return time.Duration(correction)
+}
+
+func (c *DCSyncController) Reset() {
+	c.integral = 0
+	c.Delta = 0
+}
@@ -0,0 +1,149 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+*/
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// SII word addresses
+const (
+	EC_SII_ALIAS        = 0x0004
+	EC_SII_CHECKSUM     = 0x0007
+	EC_SII_VENDOR       = 0x0008
+	EC_SII_PRODUCT      = 0x000A
+	EC_SII_REVISION     = 0x000C
+	EC_SII_SERIAL       = 0x000E
+	EC_SII_MBX_PROTOCOL = 0x001C
+	EC_SII_SIZE         = 0x003E
+	EC_SII_VERSION      = 0x003F
+	EC_SII_CATEGORIES   = 0x0040
+)
+
+// Reads the whole SII EEPROM of slave, sized from its size word
+func (m *Master) ReadEEPROM(slave uint16) ([]byte, error) {
+	if slave < 1 || slave > m.SlaveCount {
+		return nil, fmt.Errorf("no slave %d", slave)
+	}
+	defer C.ecx_eeprom2pdi(m.context, C.uint16(slave))
+
+	// size in kbit - 1
+	v, err := m.readEEPROMWords(slave, EC_SII_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	sizeWord := v & 0xFFFF
+	if sizeWord == 0xFFFF {
+		return nil, fmt.Errorf("EEPROM of slave %d is blank", slave)
+	}
+	size := int(sizeWord+1) * 128
+
+	data := make([]byte, size)
+	for word := 0; word < size/2; word += 2 {
+		v, err := m.readEEPROMWords(slave, uint16(word))
+		if err != nil {
+			return nil, err
+		}
+		binary.LittleEndian.PutUint32(data[word*2:], v)
+	}
+
+	return data, nil
+}
+
+// Reads the two EEPROM words at address. ecx_readeeprom returns 0 on
+// failure, which is also valid data, so the EEPROM status register is
+// checked after each read.
+func (m *Master) readEEPROMWords(slave, address uint16) (uint32, error) {
+	v := uint32(C.ecx_readeeprom(m.context, C.uint16(slave), C.uint16(address), EC_TIMEOUTEEP))
+
+	var buf [2]byte
+	if err := m.readRegister(slave, C.ECT_REG_EEPSTAT, buf[:]); err != nil {
+		return 0, err
+	}
+	status := binary.LittleEndian.Uint16(buf[:])
+	if status&(C.EC_ESTAT_BUSY|escEEPROMNack|escEEPROMWriteErr) != 0 {
+		return 0, fmt.Errorf("error reading EEPROM word 0x%04x of slave %d (status 0x%04x)", address, slave, status)
+	}
+	return v, nil
+}
+
+// Writes data to the SII EEPROM of slave from word 0. Only words that differ
+// from the EEPROM contents are written. The SII cache is invalidated, but the
+// slave only uses new identity or alias data once the EEPROM is reloaded.
+func (m *Master) WriteEEPROM(slave uint16, data []byte) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+	if len(data)%2 != 0 {
+		return fmt.Errorf("EEPROM data must be a whole number of words, got %d bytes", len(data))
+	}
+
+	current, err := m.ReadEEPROM(slave)
+	if err != nil {
+		return err
+	}
+	if len(data) > len(current) {
+		return fmt.Errorf("%d bytes exceeds the %d byte EEPROM of slave %d", len(data), len(current), slave)
+	}
+
+	defer m.invalidateSII(slave)
+	for word := 0; word < len(data)/2; word++ {
+		v := binary.LittleEndian.Uint16(data[word*2:])
+		if v == binary.LittleEndian.Uint16(current[word*2:]) {
+			continue
+		}
+		if err := m.writeEEPROMWord(slave, uint16(word), v); err != nil {
+			return err
+		}
+	}
+
+	C.ecx_eeprom2pdi(m.context, C.uint16(slave))
+	return nil
+}
+
+func (m *Master) writeEEPROMWord(slave, address, value uint16) error {
+	if C.ecx_writeeeprom(m.context, C.uint16(slave), C.uint16(address), C.uint16(value), EC_TIMEOUTEEP) <= 0 {
+		return fmt.Errorf("error writing EEPROM word 0x%04x of slave %d", address, slave)
+	}
+	return nil
+}
+
+// Gives the master control of the slave's EEPROM
+func (m *Master) EEPROMToMaster(slave uint16) error {
+	if C.ecx_eeprom2master(m.context, C.uint16(slave)) <= 0 {
+		return fmt.Errorf("error taking control of EEPROM of slave %d", slave)
+	}
+	return nil
+}
+
+// Gives the slave's PDI control of its EEPROM
+func (m *Master) EEPROMToPDI(slave uint16) error {
+	if C.ecx_eeprom2pdi(m.context, C.uint16(slave)) <= 0 {
+		return fmt.Errorf("error handing EEPROM of slave %d to PDI", slave)
+	}
+	return nil
+}
+
+// SOEM caches the SII of the last slave read by ecx_siigetbyte
+func (m *Master) invalidateSII(slave uint16) {
+	if uint16(m.context.esislave) == slave {
+		m.context.esislave = 0
+	}
+}
+
+// Reads and decodes the SII EEPROM of slave
+func (m *Master) ReadSII(slave uint16) (*SII, error) {
+	data, err := m.ReadEEPROM(slave)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeSII(data)
+}
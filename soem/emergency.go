@@ -0,0 +1,145 @@
+package soem
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A CoE emergency message
+type Emergency struct {
+	Time  time.Time
+	Slave uint16
+
+	// CiA 301 emergency error code
+	ErrorCode uint16
+	// Copy of object 0x1001 at the time of the emergency
+	ErrorRegister ErrorRegister
+	// Manufacturer specific error field
+	Data [5]byte
+}
+
+type EmergencyHandler func(*Emergency)
+
+type ErrorRegister uint8
+
+const (
+	ERR_REG_GENERIC        ErrorRegister = 0x01
+	ERR_REG_CURRENT        ErrorRegister = 0x02
+	ERR_REG_VOLTAGE        ErrorRegister = 0x04
+	ERR_REG_TEMPERATURE    ErrorRegister = 0x08
+	ERR_REG_COMMUNICATION  ErrorRegister = 0x10
+	ERR_REG_DEVICE_PROFILE ErrorRegister = 0x20
+	ERR_REG_MANUFACTURER   ErrorRegister = 0x80
+)
+
+var errorRegisterNames = []struct {
+	bit  ErrorRegister
+	name string
+}{
+	{ERR_REG_GENERIC, "generic"},
+	{ERR_REG_CURRENT, "current"},
+	{ERR_REG_VOLTAGE, "voltage"},
+	{ERR_REG_TEMPERATURE, "temperature"},
+	{ERR_REG_COMMUNICATION, "communication"},
+	{ERR_REG_DEVICE_PROFILE, "device profile"},
+	{ERR_REG_MANUFACTURER, "manufacturer"},
+}
+
+func (r ErrorRegister) String() string {
+	names := []string{}
+	for _, n := range errorRegisterNames {
+		if r&n.bit != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Standard error code meanings from CiA 301 and ETG.1000.6. Codes are looked
+// up exactly first, then by their 0xFF00 and 0xF000 class.
+var emergencyErrorCodes = map[uint16]string{
+	0x0000: "error reset or no error",
+	0x1000: "generic error",
+	0x2000: "current",
+	0x2100: "current, device input side",
+	0x2200: "current inside the device",
+	0x2300: "current, device output side",
+	0x3000: "voltage",
+	0x3100: "mains voltage",
+	0x3200: "voltage inside the device",
+	0x3300: "output voltage",
+	0x4000: "temperature",
+	0x4100: "ambient temperature",
+	0x4200: "device temperature",
+	0x5000: "device hardware",
+	0x6000: "device software",
+	0x6100: "internal software",
+	0x6200: "user software",
+	0x6300: "data set",
+	0x7000: "additional modules",
+	0x8000: "monitoring",
+	0x8100: "communication",
+	0x8110: "CAN overrun (objects lost)",
+	0x8120: "CAN in error passive mode",
+	0x8130: "life guard error or heartbeat error",
+	0x8140: "recovered from bus off",
+	0x8150: "CAN-ID collision",
+	0x8200: "protocol error",
+	0x8210: "PDO not processed due to length error",
+	0x8220: "PDO length exceeded",
+	0x8230: "DAM MPDO not processed, destination object not available",
+	0x8240: "unexpected SYNC data length",
+	0x8250: "RPDO timeout",
+	0x9000: "external error",
+	0xA000: "EtherCAT state machine transition error",
+	0xF000: "additional functions",
+	0xFF00: "device specific",
+}
+
+func (e *Emergency) Description() string {
+	for _, mask := range []uint16{0xFFFF, 0xFF00, 0xF000} {
+		if s, ok := emergencyErrorCodes[e.ErrorCode&mask]; ok {
+			return s
+		}
+	}
+	return "unknown error"
+}
+
+func (e *Emergency) String() string {
+	return fmt.Sprintf("slave %d: %s (error 0x%04x, register 0x%02x [%s], data % x)",
+		e.Slave, e.Description(), e.ErrorCode, uint8(e.ErrorRegister), e.ErrorRegister, e.Data)
+}
+
+// Registers handler to be called with emergencies from slave, or from all
+// slaves if slave is 0. Handlers run from DrainErrors.
+func (m *Master) OnEmergency(slave uint16, handler EmergencyHandler) {
+	m.errMu.Lock()
+	defer m.errMu.Unlock()
+
+	if m.emergencyHandlers == nil {
+		m.emergencyHandlers = make(map[uint16][]EmergencyHandler)
+	}
+	m.emergencyHandlers[slave] = append(m.emergencyHandlers[slave], handler)
+}
+
+func (m *Master) dispatchEmergency(e *ErrorEvent) {
+	emcy := &Emergency{
+		Time:          e.Time,
+		Slave:         e.Slave,
+		ErrorCode:     e.ErrorCode,
+		ErrorRegister: ErrorRegister(e.ErrorRegister),
+		Data:          e.EmergencyData,
+	}
+
+	m.errMu.Lock()
+	handlers := append([]EmergencyHandler{}, m.emergencyHandlers[0]...)
+	if emcy.Slave != 0 {
+		handlers = append(handlers, m.emergencyHandlers[emcy.Slave]...)
+	}
+	m.errMu.Unlock()
+
+	for _, h := range handlers {
+		h(emcy)
+	}
+}
@@ -0,0 +1,166 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <soem/ethercat.h>
+
+// eoe_param_t flags are bitfields, which cgo cannot set
+static int soem_eoe_set_ip(ecx_contextt *context, uint16 slave, uint8 port,
+	const uint8 *mac, const uint8 *ip, const uint8 *subnet,
+	const uint8 *gateway, const uint8 *dns, const char *dns_name, int timeout)
+{
+	eoe_param_t param;
+
+	memset(&param, 0, sizeof(param));
+	if (mac) {
+		memcpy(param.mac.addr, mac, 6);
+		param.mac_set = 1;
+	}
+	if (ip) {
+		EOE_IP4_ADDR_TO_U32(&param.ip, ip[0], ip[1], ip[2], ip[3]);
+		param.ip_set = 1;
+	}
+	if (subnet) {
+		EOE_IP4_ADDR_TO_U32(&param.subnet, subnet[0], subnet[1], subnet[2], subnet[3]);
+		param.subnet_set = 1;
+	}
+	if (gateway) {
+		EOE_IP4_ADDR_TO_U32(&param.default_gateway, gateway[0], gateway[1], gateway[2], gateway[3]);
+		param.default_gateway_set = 1;
+	}
+	if (dns) {
+		EOE_IP4_ADDR_TO_U32(&param.dns_ip, dns[0], dns[1], dns[2], dns[3]);
+		param.dns_ip_set = 1;
+	}
+	if (dns_name) {
+		strncpy(param.dns_name, dns_name, sizeof(param.dns_name) - 1);
+		param.dns_name_set = 1;
+	}
+
+	return ecx_EOEsetIp(context, slave, port, &param, timeout);
+}
+
+*/
+import "C"
+import (
+	"fmt"
+	"net"
+	"unsafe"
+)
+
+// Largest Ethernet frame tunnelled over EoE, including VLAN tag
+const EC_EOE_MAXFRAME = 1522
+
+// IP parameters of an EoE port. Nil or empty fields are left unchanged.
+type EoEParams struct {
+	MAC     net.HardwareAddr
+	IP      net.IP
+	Subnet  net.IPMask
+	Gateway net.IP
+	DNS     net.IP
+	DNSName string
+}
+
+func ip4Ptr(ip []byte) (*C.uint8, error) {
+	if ip == nil {
+		return nil, nil
+	}
+	if ip4 := net.IP(ip).To4(); ip4 != nil {
+		return (*C.uint8)(C.CBytes(ip4)), nil
+	}
+	if len(ip) == net.IPv4len {
+		return (*C.uint8)(C.CBytes(ip)), nil
+	}
+	return nil, fmt.Errorf("%s is not an IPv4 address", net.IP(ip))
+}
+
+// Sets the IP parameters of EoE port on slave
+func (m *Master) SetEoEParams(slave uint16, port uint8, params EoEParams) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+
+	var mac *C.uint8
+	if params.MAC != nil {
+		if len(params.MAC) != 6 {
+			return fmt.Errorf("%s is not a 48-bit MAC address", params.MAC)
+		}
+		mac = (*C.uint8)(C.CBytes(params.MAC))
+		defer C.free(unsafe.Pointer(mac))
+	}
+
+	var addrs [4]*C.uint8
+	for i, ip := range [][]byte{params.IP, params.Subnet, params.Gateway, params.DNS} {
+		p, err := ip4Ptr(ip)
+		if err != nil {
+			return err
+		}
+		if p != nil {
+			defer C.free(unsafe.Pointer(p))
+		}
+		addrs[i] = p
+	}
+
+	var dnsName *C.char
+	if params.DNSName != "" {
+		dnsName = C.CString(params.DNSName)
+		defer C.free(unsafe.Pointer(dnsName))
+	}
+
+	wkc := C.soem_eoe_set_ip(m.context, C.uint16(slave), C.uint8(port),
+		mac, addrs[0], addrs[1], addrs[2], addrs[3], dnsName, EC_TIMEOUTRXM)
+	if wkc <= 0 {
+		return eoeError(slave, "setting IP parameters", int(wkc))
+	}
+	return nil
+}
+
+// Sends an Ethernet frame to EoE port on slave, fragmenting it as needed
+func (m *Master) EoESend(slave uint16, port uint8, frame []byte) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+	if len(frame) == 0 {
+		return nil
+	}
+
+	wkc := C.ecx_EOEsend(m.context, C.uint16(slave), C.uint8(port),
+		C.int(len(frame)), unsafe.Pointer(&frame[0]), EC_TIMEOUTRXM)
+	if wkc <= 0 {
+		return eoeError(slave, "sending frame", int(wkc))
+	}
+	return nil
+}
+
+// Receives an Ethernet frame from EoE port on slave into buf, returning its
+// length. A length of 0 without error means no frame arrived within timeout.
+func (m *Master) EoEReceive(slave uint16, port uint8, buf []byte, timeout int) (int, error) {
+	if slave < 1 || slave > m.SlaveCount {
+		return 0, fmt.Errorf("no slave %d", slave)
+	}
+	if len(buf) == 0 {
+		return 0, fmt.Errorf("empty receive buffer")
+	}
+
+	size := C.int(len(buf))
+	wkc := C.ecx_EOErecv(m.context, C.uint16(slave), C.uint8(port),
+		&size, unsafe.Pointer(&buf[0]), C.int(timeout))
+	if wkc == 0 {
+		return 0, nil
+	}
+	if wkc < 0 {
+		return 0, eoeError(slave, "receiving frame", int(wkc))
+	}
+	return int(size), nil
+}
+
+func eoeError(slave uint16, op string, wkc int) error {
+	if EtherCATErrorType(-wkc) == EC_ERR_TYPE_EOE_INVALID_RX_DATA {
+		return fmt.Errorf("EoE %s on slave %d: invalid data received", op, slave)
+	}
+	return fmt.Errorf("EoE %s on slave %d failed (wkc %d)", op, slave, wkc)
+}
@@ -0,0 +1,141 @@
+//go:build linux && (386 || amd64 || arm || arm64 || loong64 || riscv64 || s390x)
+// +build linux
+// +build 386 amd64 arm arm64 loong64 riscv64 s390x
+
+package soem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// TUNSETIFF is _IOW('T', 202, int), which has this value on architectures
+// using the generic ioctl encoding. mips, ppc and sparc encode the direction
+// differently, hence the build constraint.
+const (
+	tunSetIFF = 0x400454ca
+	iffTAP    = 0x0002
+	iffNoPI   = 0x1000
+)
+
+// Bridges the Ethernet frames tunnelled through an EoE port of a slave to a
+// Linux TAP interface, so hosts behind the slave can be reached over IP.
+// The TAP interface must be brought up and addressed separately.
+//
+// The bridge polls the slave's mailbox, so other mailbox transfers with the
+// same slave should not run concurrently with it.
+type EoEBridge struct {
+	// Mailbox poll interval while no frames are arriving
+	PollInterval time.Duration
+
+	master *Master
+	slave  uint16
+	port   uint8
+	tap    *os.File
+	name   string
+}
+
+// Creates TAP interface name, or attaches to it if it exists, and bridges
+// it to EoE port on slave
+func NewEoEBridge(master *Master, slave uint16, port uint8, name string) (*EoEBridge, error) {
+	if len(name) >= 16 {
+		return nil, fmt.Errorf("interface name %s is too long", name)
+	}
+
+	fd, err := syscall.Open("/dev/net/tun", syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error opening /dev/net/tun: %w", err)
+	}
+
+	var ifr struct {
+		name  [16]byte
+		flags uint16
+		_     [22]byte
+	}
+	copy(ifr.name[:], name)
+	ifr.flags = iffTAP | iffNoPI
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), tunSetIFF, uintptr(unsafe.Pointer(&ifr))); errno != 0 {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("error creating TAP interface %s: %w", name, errno)
+	}
+
+	// non-blocking so Close interrupts a pending read
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	return &EoEBridge{
+		PollInterval: time.Millisecond,
+		master:       master,
+		slave:        slave,
+		port:         port,
+		tap:          os.NewFile(uintptr(fd), "/dev/net/tun"),
+		name:         name,
+	}, nil
+}
+
+// Name of the TAP interface
+func (b *EoEBridge) Name() string {
+	return b.name
+}
+
+// Forwards frames in both directions until ctx is cancelled or forwarding
+// fails. Frames the TAP interface hands over are sent to the slave between
+// mailbox polls.
+func (b *EoEBridge) Run(ctx context.Context) error {
+	outgoing := make(chan []byte, 16)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			frame := make([]byte, EC_EOE_MAXFRAME)
+			n, err := b.tap.Read(frame)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case outgoing <- frame[:n]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, EC_EOE_MAXFRAME)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return fmt.Errorf("error reading TAP interface %s: %w", b.name, err)
+		case frame := <-outgoing:
+			if err := b.master.EoESend(b.slave, b.port, frame); err != nil {
+				return err
+			}
+			continue
+		default:
+		}
+
+		n, err := b.master.EoEReceive(b.slave, b.port, buf, EC_TIMEOUTRET)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			time.Sleep(b.PollInterval)
+			continue
+		}
+		if _, err := b.tap.Write(buf[:n]); err != nil {
+			return fmt.Errorf("error writing TAP interface %s: %w", b.name, err)
+		}
+	}
+}
+
+// Closes the TAP interface. A non-persistent interface is removed.
+func (b *EoEBridge) Close() error {
+	return b.tap.Close()
+}
@@ -0,0 +1,203 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+static int32 soem_error_abortcode(ec_errort *e) {
+	return e->AbortCode;
+}
+
+static uint16 soem_error_errorcode(ec_errort *e) {
+	return e->ErrorCode;
+}
+
+static void soem_error_emergency(ec_errort *e, uint8 *reg, uint8 *data) {
+	*reg = e->ErrorReg;
+	data[0] = e->b1;
+	data[1] = e->w1 & 0xff;
+	data[2] = e->w1 >> 8;
+	data[3] = e->w2 & 0xff;
+	data[4] = e->w2 >> 8;
+}
+
+*/
+import "C"
+import (
+	"fmt"
+	"time"
+)
+
+// An entry from the master's error list
+type ErrorEvent struct {
+	Time     time.Time
+	Slave    uint16
+	Index    uint16
+	SubIndex uint8
+	Type     EtherCATErrorType
+
+	// SDO abort code
+	AbortCode uint32
+
+	// Error code for emergency, packet, mailbox and SoE errors
+	ErrorCode uint16
+	// Emergency error register
+	ErrorRegister uint8
+	// Emergency manufacturer specific error field
+	EmergencyData [5]byte
+}
+
+func newErrorEvent(cerr *C.ec_errort) *ErrorEvent {
+	e := &ErrorEvent{
+		Time:     time.Unix(int64(cerr.Time.sec), int64(cerr.Time.usec)*int64(time.Microsecond)),
+		Slave:    uint16(cerr.Slave),
+		Index:    uint16(cerr.Index),
+		SubIndex: uint8(cerr.SubIdx),
+		Type:     EtherCATErrorType(cerr.Etype),
+	}
+
+	switch e.Type {
+	case EC_ERR_TYPE_EMERGENCY:
+		var reg C.uint8
+		var data [5]C.uint8
+		e.ErrorCode = uint16(C.soem_error_errorcode(cerr))
+		C.soem_error_emergency(cerr, &reg, &data[0])
+		e.ErrorRegister = uint8(reg)
+		for i, b := range data {
+			e.EmergencyData[i] = byte(b)
+		}
+	case EC_ERR_TYPE_PACKET_ERROR, EC_ERR_TYPE_MBX_ERROR, EC_ERR_TYPE_SOE_ERROR:
+		e.ErrorCode = uint16(C.soem_error_errorcode(cerr))
+	default:
+		e.AbortCode = uint32(C.soem_error_abortcode(cerr))
+	}
+
+	return e
+}
+
+func (e *ErrorEvent) Error() string {
+	switch e.Type {
+	case EC_ERR_TYPE_SDO_ERROR:
+		return fmt.Sprintf("SDO error slave %d index 0x%04x:%02x abort 0x%08x %s",
+			e.Slave, e.Index, e.SubIndex, e.AbortCode,
+			C.GoString(C.ec_sdoerror2string(C.uint32(e.AbortCode))))
+	case EC_ERR_TYPE_EMERGENCY:
+		return fmt.Sprintf("EMERGENCY slave %d error 0x%04x register 0x%02x data % x",
+			e.Slave, e.ErrorCode, e.ErrorRegister, e.EmergencyData)
+	case EC_ERR_TYPE_PACKET_ERROR:
+		return fmt.Sprintf("PACKET error slave %d index 0x%04x:%02x error %d",
+			e.Slave, e.Index, e.SubIndex, e.ErrorCode)
+	case EC_ERR_TYPE_MBX_ERROR:
+		return fmt.Sprintf("MBX error slave %d error 0x%04x %s",
+			e.Slave, e.ErrorCode, C.GoString(C.ec_mbxerror2string(C.uint16(e.ErrorCode))))
+	case EC_ERR_TYPE_SOE_ERROR:
+		return fmt.Sprintf("SoE error slave %d IDN 0x%04x error 0x%04x %s",
+			e.Slave, e.Index, e.ErrorCode, C.GoString(C.ec_soeerror2string(C.uint16(e.ErrorCode))))
+	default:
+		return fmt.Sprintf("%s slave %d index 0x%04x:%02x code 0x%08x",
+			e.Type, e.Slave, e.Index, e.SubIndex, e.AbortCode)
+	}
+}
+
+func (m *Master) IsError() bool {
+	m.errMu.Lock()
+	pending := len(m.pendingErrors) > 0
+	m.errMu.Unlock()
+
+	return pending || m.isError()
+}
+
+// Returns the oldest error in the queue, or nil if the queue is empty
+func (m *Master) PopError() *ErrorEvent {
+	m.errMu.Lock()
+	defer m.errMu.Unlock()
+
+	if len(m.pendingErrors) > 0 {
+		e := m.pendingErrors[0]
+		m.pendingErrors = m.pendingErrors[1:]
+		return e
+	}
+
+	return m.popError()
+}
+
+// Empties the error queue, delivering each error to the channels registered
+// with NotifyErrors and each emergency to the handlers registered with
+// OnEmergency
+func (m *Master) DrainErrors() []*ErrorEvent {
+	var errs []*ErrorEvent
+	for e := m.PopError(); e != nil; e = m.PopError() {
+		errs = append(errs, e)
+	}
+
+	m.errMu.Lock()
+	for _, e := range errs {
+		for _, ch := range m.errorChans {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	}
+	m.errMu.Unlock()
+
+	for _, e := range errs {
+		if e.Type == EC_ERR_TYPE_EMERGENCY {
+			m.dispatchEmergency(e)
+		}
+	}
+
+	return errs
+}
+
+// Relays errors drained by DrainErrors to ch. Sends do not block, so a
+// receiver that falls behind misses errors.
+func (m *Master) NotifyErrors(ch chan<- *ErrorEvent) {
+	m.errMu.Lock()
+	m.errorChans = append(m.errorChans, ch)
+	m.errMu.Unlock()
+}
+
+func (m *Master) StopErrors(ch chan<- *ErrorEvent) {
+	m.errMu.Lock()
+	defer m.errMu.Unlock()
+
+	for i, c := range m.errorChans {
+		if c == ch {
+			m.errorChans = append(m.errorChans[:i], m.errorChans[i+1:]...)
+			return
+		}
+	}
+}
+
+// Pops the first error matching match from SOEM's error list. Errors that
+// do not match are kept for PopError.
+func (m *Master) findError(match func(*ErrorEvent) bool) *ErrorEvent {
+	m.errMu.Lock()
+	defer m.errMu.Unlock()
+
+	for e := m.popError(); e != nil; e = m.popError() {
+		if match(e) {
+			return e
+		}
+		m.pendingErrors = append(m.pendingErrors, e)
+	}
+
+	return nil
+}
+
+func (m *Master) popError() *ErrorEvent {
+	if !m.isError() {
+		return nil
+	}
+
+	var cerr C.ec_errort
+	if C.ecx_poperror(m.context, &cerr) == 0 {
+		return nil
+	}
+
+	return newErrorEvent(&cerr)
+}
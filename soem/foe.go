@@ -0,0 +1,190 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+extern int soemFOEhook(uint16 slave, int packetnumber, int datasize);
+
+// Points the slave's mailbox sync managers at its bootstrap mailbox, as
+// required before requesting BOOT
+static void soem_boot_mailbox(ecx_contextt *context, uint16 slave)
+{
+	ec_slavet *s = &context->slavelist[slave];
+	uint32 data;
+
+	data = ecx_readeeprom(context, slave, ECT_SII_BOOTRXMBX, EC_TIMEOUTEEP);
+	s->SM[0].StartAddr = (uint16)(data & 0xffff);
+	s->SM[0].SMlength = (uint16)(data >> 16);
+	s->mbx_wo = (uint16)(data & 0xffff);
+	s->mbx_l = (uint16)(data >> 16);
+
+	data = ecx_readeeprom(context, slave, ECT_SII_BOOTTXMBX, EC_TIMEOUTEEP);
+	s->SM[1].StartAddr = (uint16)(data & 0xffff);
+	s->SM[1].SMlength = (uint16)(data >> 16);
+	s->mbx_ro = (uint16)(data & 0xffff);
+	s->mbx_rl = (uint16)(data >> 16);
+
+	ecx_FPWR(context->port, s->configadr, ECT_REG_SM0, sizeof(ec_smt), &s->SM[0], EC_TIMEOUTRET);
+	ecx_FPWR(context->port, s->configadr, ECT_REG_SM1, sizeof(ec_smt), &s->SM[1], EC_TIMEOUTRET);
+}
+
+*/
+import "C"
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// Called after each FoE packet with the bytes transferred so far. total is
+// the file size for writes and 0 for reads, where it is not known up front.
+type FoEProgress func(slave uint16, done, total int)
+
+// FoE error returned by the slave or detected by SOEM
+type FoEError struct {
+	Slave    uint16
+	Filename string
+	Type     EtherCATErrorType
+}
+
+func (e *FoEError) Error() string {
+	switch e.Type {
+	case EC_ERR_TYPE_FOE_BUF2SMALL:
+		return fmt.Sprintf("FoE transfer of %s from slave %d does not fit the buffer", e.Filename, e.Slave)
+	case EC_ERR_TYPE_FOE_PACKETNUMBER:
+		return fmt.Sprintf("FoE transfer of %s on slave %d lost a packet", e.Filename, e.Slave)
+	case EC_ERR_TYPE_FOE_FILE_NOTFOUND:
+		return fmt.Sprintf("FoE file %s not found on slave %d", e.Filename, e.Slave)
+	default:
+		return fmt.Sprintf("FoE transfer of %s on slave %d failed", e.Filename, e.Slave)
+	}
+}
+
+// SOEM's FoE hook carries no context, so transfers are serialised across
+// masters and the active transfer's progress callback is kept here
+var (
+	foeMu       sync.Mutex
+	foeProgress func(slave uint16, packet, size int)
+)
+
+//export soemFOEhook
+func soemFOEhook(slave C.uint16, packetnumber C.int, datasize C.int) C.int {
+	if foeProgress != nil {
+		foeProgress(uint16(slave), int(packetnumber), int(datasize))
+	}
+	return 0
+}
+
+func (m *Master) beginFoE(progress func(slave uint16, packet, size int)) {
+	foeMu.Lock()
+	foeProgress = progress
+	if progress != nil {
+		C.ecx_FOEdefinehook(m.context, unsafe.Pointer(C.soemFOEhook))
+	} else {
+		C.ecx_FOEdefinehook(m.context, nil)
+	}
+}
+
+func (m *Master) endFoE() {
+	C.ecx_FOEdefinehook(m.context, nil)
+	foeProgress = nil
+	foeMu.Unlock()
+}
+
+// Reads a file of at most size bytes from slave
+func (m *Master) FoERead(slave uint16, filename string, password uint32, size int, progress FoEProgress) ([]byte, error) {
+	if slave < 1 || slave > m.SlaveCount {
+		return nil, fmt.Errorf("no slave %d", slave)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid FoE read size %d for %s", size, filename)
+	}
+
+	var hook func(uint16, int, int)
+	if progress != nil {
+		// SOEM reports the bytes read so far
+		hook = func(slave uint16, _, read int) {
+			progress(slave, read, 0)
+		}
+	}
+
+	cname := C.CString(filename)
+	defer C.free(unsafe.Pointer(cname))
+	buf := C.malloc(C.size_t(size))
+	defer C.free(buf)
+
+	m.beginFoE(hook)
+	psize := C.int(size)
+	wkc := C.ecx_FOEread(m.context, C.uint16(slave), cname, C.uint32(password), &psize, buf, EC_TIMEOUTSTATE)
+	m.endFoE()
+
+	if wkc <= 0 {
+		return nil, foeError(slave, filename, int(wkc))
+	}
+	return C.GoBytes(buf, psize), nil
+}
+
+// Writes data to a file on slave
+func (m *Master) FoEWrite(slave uint16, filename string, password uint32, data []byte, progress FoEProgress) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+
+	var hook func(uint16, int, int)
+	if progress != nil {
+		// SOEM reports the bytes left to send
+		hook = func(slave uint16, _, remaining int) {
+			progress(slave, len(data)-remaining, len(data))
+		}
+	}
+
+	cname := C.CString(filename)
+	defer C.free(unsafe.Pointer(cname))
+	buf := C.CBytes(data)
+	defer C.free(buf)
+
+	m.beginFoE(hook)
+	wkc := C.ecx_FOEwrite(m.context, C.uint16(slave), cname, C.uint32(password), C.int(len(data)), buf, EC_TIMEOUTSTATE)
+	m.endFoE()
+
+	if wkc <= 0 {
+		return foeError(slave, filename, int(wkc))
+	}
+	return nil
+}
+
+// SOEM returns FoE failures as the negated error type
+func foeError(slave uint16, filename string, wkc int) error {
+	switch t := EtherCATErrorType(-wkc); t {
+	case EC_ERR_TYPE_FOE_ERROR, EC_ERR_TYPE_FOE_BUF2SMALL,
+		EC_ERR_TYPE_FOE_PACKETNUMBER, EC_ERR_TYPE_FOE_FILE_NOTFOUND:
+		return &FoEError{Slave: slave, Filename: filename, Type: t}
+	}
+	return fmt.Errorf("FoE transfer of %s on slave %d failed (wkc %d)", filename, slave, wkc)
+}
+
+// Updates the firmware of slave: the slave is taken to INIT, its bootstrap
+// mailbox is configured and it is put in BOOT, the firmware is written over
+// FoE and the slave is returned to INIT. The slave must be reconfigured, or
+// usually power cycled, before it is used again.
+func (m *Master) UpdateFirmware(slave uint16, filename string, password uint32, firmware []byte, progress FoEProgress) error {
+	if err := m.SetSlaveState(slave, EC_STATE_INIT, EC_TIMEOUTSTATE); err != nil {
+		return err
+	}
+
+	C.soem_boot_mailbox(m.context, C.uint16(slave))
+
+	if err := m.SetSlaveState(slave, EC_STATE_BOOT, EC_TIMEOUTSTATE*10); err != nil {
+		return fmt.Errorf("slave %d did not enter BOOT: %w", slave, err)
+	}
+
+	if err := m.FoEWrite(slave, filename, password, firmware, progress); err != nil {
+		return err
+	}
+
+	return m.SetSlaveState(slave, EC_STATE_INIT, EC_TIMEOUTSTATE)
+}
@@ -0,0 +1,205 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+extern int soemPO2SOconfig(ecx_contextt *context, uint16 slave);
+
+*/
+import "C"
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// Masters by context, for routing callbacks from SOEM back to Go
+var masters sync.Map
+
+func registerMaster(m *Master) {
+	masters.Store(uintptr(unsafe.Pointer(m.context)), m)
+}
+
+func unregisterMaster(m *Master) {
+	masters.Delete(uintptr(unsafe.Pointer(m.context)))
+}
+
+func lookupMaster(context *C.ecx_contextt) *Master {
+	if m, ok := masters.Load(uintptr(unsafe.Pointer(context))); ok {
+		return m.(*Master)
+	}
+	return nil
+}
+
+// Configuration run on a slave in PRE_OP before it is requested to go to
+// SAFE_OP, typically SDO writes of slave specific settings
+type PreOpHook func(*Slave) error
+
+// An SDO written to every slave of a given type during PRE_OP to SAFE_OP
+type StartupSDO struct {
+	Index          uint16
+	SubIndex       uint8
+	CompleteAccess bool
+	DataType       EtherCATDataType
+	Value          interface{}
+}
+
+func (s StartupSDO) String() string {
+	return fmt.Sprintf("0x%04x:%02x %s = %v", s.Index, s.SubIndex, s.DataType, s.Value)
+}
+
+type deviceID struct {
+	vendorID    uint32
+	productCode uint32
+}
+
+// Failure of a PRE_OP to SAFE_OP configuration hook
+type HookError struct {
+	Slave uint16
+	Err   error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("PRE_OP to SAFE_OP configuration of slave %d failed: %s", e.Slave, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// SOEM calls this for each slave while mapping it or reconfiguring it after
+// recovery, in PRE_OP just before the slave is requested to go to SAFE_OP
+//
+//export soemPO2SOconfig
+func soemPO2SOconfig(context *C.ecx_contextt, slave C.uint16) C.int {
+	m := lookupMaster(context)
+	if m == nil {
+		return 0
+	}
+
+	if err := m.runPreOpHooks(uint16(slave)); err != nil {
+		m.hookMu.Lock()
+		if m.hookErrs == nil {
+			m.hookErrs = map[uint16]*HookError{}
+		}
+		if m.hookErrs[uint16(slave)] == nil {
+			m.hookErrs[uint16(slave)] = &HookError{uint16(slave), err}
+		}
+		m.hookMu.Unlock()
+		return 0
+	}
+	return 1
+}
+
+// Points every slave's PO2SOconfigx hook at the Go trampoline. ConfigInit
+// clears the slave list, so this is repeated after every ConfigInit.
+func (m *Master) installHooks() {
+	for slave := uint16(1); slave <= m.SlaveCount; slave++ {
+		m.ecSlave(slave).PO2SOconfigx = (*[0]byte)(C.soemPO2SOconfig)
+	}
+}
+
+// Registers hook to run every time slave is brought from PRE_OP to SAFE_OP,
+// by ConfigMap or when the slave is reconfigured after recovery. Hooks run
+// after any startup SDOs and PDO mapping, in the order they were added.
+func (m *Master) OnPreOpToSafeOp(slave uint16, hook PreOpHook) {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+
+	if m.preOpHooks == nil {
+		m.preOpHooks = make(map[uint16][]PreOpHook)
+	}
+	m.preOpHooks[slave] = append(m.preOpHooks[slave], hook)
+}
+
+// Adds SDOs to write to every slave matching vendorID and productCode when
+// it is brought from PRE_OP to SAFE_OP. SDOs are written in the order added.
+func (m *Master) AddStartupSDOs(vendorID, productCode uint32, sdos ...StartupSDO) {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+
+	if m.startupSDOs == nil {
+		m.startupSDOs = make(map[deviceID][]StartupSDO)
+	}
+	id := deviceID{vendorID, productCode}
+	m.startupSDOs[id] = append(m.startupSDOs[id], sdos...)
+}
+
+func (m *Master) runPreOpHooks(slave uint16) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return nil
+	}
+	s := m.Slaves[slave-1]
+
+	m.hookMu.Lock()
+	sdos := append([]StartupSDO{}, m.startupSDOs[deviceID{s.VendorID, s.ProductCode}]...)
+	mapping, hasMapping := m.pdoMappings[slave]
+	hooks := append([]PreOpHook{}, m.preOpHooks[slave]...)
+	m.hookMu.Unlock()
+
+	for _, sdo := range sdos {
+		if err := m.writeStartupSDO(slave, sdo); err != nil {
+			return err
+		}
+	}
+
+	if hasMapping {
+		if err := m.writePDOMapping(slave, mapping); err != nil {
+			return err
+		}
+	}
+
+	for _, hook := range hooks {
+		if err := hook(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Master) writeStartupSDO(slave uint16, sdo StartupSDO) error {
+	data, err := EncodeValue(sdo.DataType, sdo.Value)
+	if err != nil {
+		return fmt.Errorf("startup SDO %s: %w", sdo, err)
+	}
+
+	if sdo.CompleteAccess {
+		return m.SDOWriteCA(slave, sdo.Index, sdo.SubIndex, data)
+	}
+	return m.SDOWrite(slave, sdo.Index, sdo.SubIndex, data)
+}
+
+// Hook errors are kept per slave, so mapping one group and reconfiguring a
+// slave of another from the supervisor do not see each other's errors
+func (m *Master) clearHookErrors(match func(slave uint16) bool) {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+
+	for slave := range m.hookErrs {
+		if match(slave) {
+			delete(m.hookErrs, slave)
+		}
+	}
+}
+
+// Returns and clears the hook error of the lowest matching slave
+func (m *Master) takeHookError(match func(slave uint16) bool) error {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+
+	var first *HookError
+	for slave, err := range m.hookErrs {
+		if match(slave) && (first == nil || slave < first.Slave) {
+			first = err
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	delete(m.hookErrs, first.Slave)
+	return first
+}
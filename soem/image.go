@@ -0,0 +1,241 @@
+package soem
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// A slave's inputs or outputs within the IO map. Offsets are relative to the
+// slave's first bit, so bit-packed slaves that share an IO map byte with
+// their neighbours are addressed from 0 like any other slave. Accessors work
+// directly on the IO map and do not allocate. A nil image has no bits, and
+// its accessors return an error.
+type ProcessImage struct {
+	data     []byte
+	startBit uint
+	bits     uint
+}
+
+func newProcessImage(buffer unsafe.Pointer, startBit uint8, bits uint16) *ProcessImage {
+	p := &ProcessImage{startBit: uint(startBit), bits: uint(bits)}
+	if buffer != nil && bits > 0 {
+		size := (p.startBit + p.bits + 7) / 8
+		p.data = (*[1 << 30]byte)(buffer)[:size:size]
+	}
+	return p
+}
+
+// Size of the image in bits
+func (p *ProcessImage) Bits() uint {
+	if p == nil {
+		return 0
+	}
+	return p.bits
+}
+
+func (p *ProcessImage) Bool(bitOffset uint) (bool, error) {
+	v, err := p.getBits(bitOffset, 1)
+	return v != 0, err
+}
+
+func (p *ProcessImage) SetBool(bitOffset uint, value bool) error {
+	v := uint64(0)
+	if value {
+		v = 1
+	}
+	return p.setBits(bitOffset, 1, v)
+}
+
+// Reads a field of up to 64 bits at any bit offset
+func (p *ProcessImage) Bitfield(bitOffset, bitLength uint) (uint64, error) {
+	return p.getBits(bitOffset, bitLength)
+}
+
+func (p *ProcessImage) SetBitfield(bitOffset, bitLength uint, value uint64) error {
+	return p.setBits(bitOffset, bitLength, value)
+}
+
+func (p *ProcessImage) Uint8(byteOffset uint) (uint8, error) {
+	v, err := p.getByteBits(byteOffset, 8)
+	return uint8(v), err
+}
+
+func (p *ProcessImage) SetUint8(byteOffset uint, value uint8) error {
+	return p.setByteBits(byteOffset, 8, uint64(value))
+}
+
+func (p *ProcessImage) Int8(byteOffset uint) (int8, error) {
+	v, err := p.getByteBits(byteOffset, 8)
+	return int8(v), err
+}
+
+func (p *ProcessImage) SetInt8(byteOffset uint, value int8) error {
+	return p.setByteBits(byteOffset, 8, uint64(uint8(value)))
+}
+
+func (p *ProcessImage) Uint16(byteOffset uint) (uint16, error) {
+	v, err := p.getByteBits(byteOffset, 16)
+	return uint16(v), err
+}
+
+func (p *ProcessImage) SetUint16(byteOffset uint, value uint16) error {
+	return p.setByteBits(byteOffset, 16, uint64(value))
+}
+
+func (p *ProcessImage) Int16(byteOffset uint) (int16, error) {
+	v, err := p.getByteBits(byteOffset, 16)
+	return int16(v), err
+}
+
+func (p *ProcessImage) SetInt16(byteOffset uint, value int16) error {
+	return p.setByteBits(byteOffset, 16, uint64(uint16(value)))
+}
+
+func (p *ProcessImage) Uint32(byteOffset uint) (uint32, error) {
+	v, err := p.getByteBits(byteOffset, 32)
+	return uint32(v), err
+}
+
+func (p *ProcessImage) SetUint32(byteOffset uint, value uint32) error {
+	return p.setByteBits(byteOffset, 32, uint64(value))
+}
+
+func (p *ProcessImage) Int32(byteOffset uint) (int32, error) {
+	v, err := p.getByteBits(byteOffset, 32)
+	return int32(v), err
+}
+
+func (p *ProcessImage) SetInt32(byteOffset uint, value int32) error {
+	return p.setByteBits(byteOffset, 32, uint64(uint32(value)))
+}
+
+func (p *ProcessImage) Uint64(byteOffset uint) (uint64, error) {
+	return p.getByteBits(byteOffset, 64)
+}
+
+func (p *ProcessImage) SetUint64(byteOffset uint, value uint64) error {
+	return p.setByteBits(byteOffset, 64, value)
+}
+
+func (p *ProcessImage) Int64(byteOffset uint) (int64, error) {
+	v, err := p.getByteBits(byteOffset, 64)
+	return int64(v), err
+}
+
+func (p *ProcessImage) SetInt64(byteOffset uint, value int64) error {
+	return p.setByteBits(byteOffset, 64, uint64(value))
+}
+
+func (p *ProcessImage) Float32(byteOffset uint) (float32, error) {
+	v, err := p.getByteBits(byteOffset, 32)
+	return math.Float32frombits(uint32(v)), err
+}
+
+func (p *ProcessImage) SetFloat32(byteOffset uint, value float32) error {
+	return p.setByteBits(byteOffset, 32, uint64(math.Float32bits(value)))
+}
+
+func (p *ProcessImage) Float64(byteOffset uint) (float64, error) {
+	v, err := p.getByteBits(byteOffset, 64)
+	return math.Float64frombits(v), err
+}
+
+func (p *ProcessImage) SetFloat64(byteOffset uint, value float64) error {
+	return p.setByteBits(byteOffset, 64, math.Float64bits(value))
+}
+
+func (p *ProcessImage) checkBounds(bitOffset, bitLength uint) error {
+	if p == nil {
+		return errors.New("process image is not mapped")
+	}
+	if bitLength < 1 || bitLength > 64 || bitOffset > p.bits || bitLength > p.bits-bitOffset {
+		return fmt.Errorf("%d bits at bit offset %d out of range of %d bit process image", bitLength, bitOffset, p.bits)
+	}
+	return nil
+}
+
+// Byte offsets are checked before converting them to bits, where a large
+// offset would overflow
+func (p *ProcessImage) getByteBits(byteOffset, bitLength uint) (uint64, error) {
+	if byteOffset > p.Bits()/8 {
+		return 0, fmt.Errorf("byte offset %d out of range of %d bit process image", byteOffset, p.Bits())
+	}
+	return p.getBits(byteOffset*8, bitLength)
+}
+
+func (p *ProcessImage) setByteBits(byteOffset, bitLength uint, value uint64) error {
+	if byteOffset > p.Bits()/8 {
+		return fmt.Errorf("byte offset %d out of range of %d bit process image", byteOffset, p.Bits())
+	}
+	return p.setBits(byteOffset*8, bitLength, value)
+}
+
+// Process data is little-endian, with bit 0 the least significant bit of
+// the first byte
+func (p *ProcessImage) getBits(bitOffset, bitLength uint) (uint64, error) {
+	if err := p.checkBounds(bitOffset, bitLength); err != nil {
+		return 0, err
+	}
+
+	bit := p.startBit + bitOffset
+	if bit%8 == 0 {
+		i := bit / 8
+		switch bitLength {
+		case 8:
+			return uint64(p.data[i]), nil
+		case 16:
+			return uint64(binary.LittleEndian.Uint16(p.data[i:])), nil
+		case 32:
+			return uint64(binary.LittleEndian.Uint32(p.data[i:])), nil
+		case 64:
+			return binary.LittleEndian.Uint64(p.data[i:]), nil
+		}
+	}
+
+	var v uint64
+	for n := uint(0); n < bitLength; n++ {
+		b := bit + n
+		if p.data[b/8]&(1<<(b%8)) != 0 {
+			v |= 1 << n
+		}
+	}
+	return v, nil
+}
+
+func (p *ProcessImage) setBits(bitOffset, bitLength uint, value uint64) error {
+	if err := p.checkBounds(bitOffset, bitLength); err != nil {
+		return err
+	}
+
+	bit := p.startBit + bitOffset
+	if bit%8 == 0 {
+		i := bit / 8
+		switch bitLength {
+		case 8:
+			p.data[i] = uint8(value)
+			return nil
+		case 16:
+			binary.LittleEndian.PutUint16(p.data[i:], uint16(value))
+			return nil
+		case 32:
+			binary.LittleEndian.PutUint32(p.data[i:], uint32(value))
+			return nil
+		case 64:
+			binary.LittleEndian.PutUint64(p.data[i:], value)
+			return nil
+		}
+	}
+
+	for n := uint(0); n < bitLength; n++ {
+		b := bit + n
+		if value&(1<<n) != 0 {
+			p.data[b/8] |= 1 << (b % 8)
+		} else {
+			p.data[b/8] &^= 1 << (b % 8)
+		}
+	}
+	return nil
+}
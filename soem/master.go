@@ -7,53 +7,181 @@ package soem
 #include <stdlib.h>
 #include <soem/ethercat.h>
 
+// Allocates a context with its own port, slave and group lists and
+// buffers, mirroring the static ecx_context SOEM provides for the legacy
+// single-master API
+static ecx_contextt *soem_context_new(void) {
+	ecx_contextt *c = calloc(1, sizeof(ecx_contextt));
+	if (c == NULL) {
+		return NULL;
+	}
+
+	c->port = calloc(1, sizeof(ecx_portt));
+	c->slavelist = calloc(EC_MAXSLAVE, sizeof(ec_slavet));
+	c->slavecount = calloc(1, sizeof(int));
+	c->maxslave = EC_MAXSLAVE;
+	c->grouplist = calloc(EC_MAXGROUP, sizeof(ec_groupt));
+	c->maxgroup = EC_MAXGROUP;
+	c->esibuf = calloc(EC_MAXEEPBUF, sizeof(uint8));
+	c->esimap = calloc(EC_MAXEEPBITMAP, sizeof(uint32));
+	c->esislave = 0;
+	c->elist = calloc(1, sizeof(ec_eringt));
+	c->idxstack = calloc(1, sizeof(ec_idxstackT));
+	c->ecaterror = calloc(1, sizeof(boolean));
+	c->DCtime = calloc(1, sizeof(int64));
+	c->SMcommtype = calloc(EC_MAX_MAPT, sizeof(ec_SMcommtypet));
+	c->PDOassign = calloc(EC_MAX_MAPT, sizeof(ec_PDOassignt));
+	c->PDOdesc = calloc(EC_MAX_MAPT, sizeof(ec_PDOdesct));
+	c->eepSM = calloc(1, sizeof(ec_eepromSMt));
+	c->eepFMMU = calloc(1, sizeof(ec_eepromFMMUt));
+	c->manualstatechange = 0;
+
+	return c;
+}
+
+static void soem_context_free(ecx_contextt *c) {
+	free(c->port);
+	free(c->slavelist);
+	free(c->slavecount);
+	free(c->grouplist);
+	free(c->esibuf);
+	free(c->esimap);
+	free(c->elist);
+	free(c->idxstack);
+	free(c->ecaterror);
+	free(c->DCtime);
+	free(c->SMcommtype);
+	free(c->PDOassign);
+	free(c->PDOdesc);
+	free(c->eepSM);
+	free(c->eepFMMU);
+	free(c);
+}
+
 */
 import "C"
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
 
 type Master struct {
+	// last working counter per group and DC time of the last exchange,
+	// accessed atomically and kept first for 64-bit alignment on 32-bit
+	// platforms
+	lastWKC     [C.EC_MAXGROUP]int64
+	dcTime      int64
+	wkcMonitors [C.EC_MAXGROUP]wkcMonitor
+
 	SlaveCount uint16
 	Slaves     []*Slave
 
-	context   C.ecx_contextt
-	ioMap     unsafe.Pointer
-	ioMapSize C.int
+	context    *C.ecx_contextt
+	redport    *C.ecx_redportt
+	ioMaps     [C.EC_MAXGROUP]unsafe.Pointer
+	ioMapSizes [C.EC_MAXGROUP]C.int
+
+	errMu         sync.Mutex
+	pendingErrors []*ErrorEvent
+	errorChans    []chan<- *ErrorEvent
+
+	emergencyHandlers map[uint16][]EmergencyHandler
+
+	expectedNetwork *NetworkConfig
+
+	hookMu      sync.Mutex
+	preOpHooks  map[uint16][]PreOpHook
+	startupSDOs map[deviceID][]StartupSDO
+	pdoMappings map[uint16]PDOMapping
+	hookErrs    map[uint16]*HookError
 }
 
-// TODO Work out
+// Each master owns its context and slave list in C memory, so masters on
+// different interfaces can be used from the same process.
 func NewSOEMMaster(ifname string) (*Master, error) {
 	soem := new(Master)
 	cifname := C.CString(ifname)
 	defer C.free(unsafe.Pointer(cifname))
 
-	soem.context = C.ecx_context // no idea why this is needed or even works
+	soem.context = C.soem_context_new()
+	if soem.context == nil {
+		return nil, errors.New("error allocating master context")
+	}
 
-	if C.ecx_init(&soem.context, cifname) <= 0 {
+	if C.ecx_init(soem.context, cifname) <= 0 {
+		C.soem_context_free(soem.context)
 		return nil, fmt.Errorf("error opening interface %s", ifname)
 	}
 
+	registerMaster(soem)
+	return soem, nil
+}
+
+// Opens a master using cable redundancy. Frames are sent on both interfaces
+// so the slaves stay reachable if the ring is broken at any one point.
+func NewRedundantMaster(primary, secondary string) (*Master, error) {
+	soem := new(Master)
+	cprimary := C.CString(primary)
+	defer C.free(unsafe.Pointer(cprimary))
+	csecondary := C.CString(secondary)
+	defer C.free(unsafe.Pointer(csecondary))
+
+	soem.context = C.soem_context_new()
+	if soem.context == nil {
+		return nil, errors.New("error allocating master context")
+	}
+	soem.redport = (*C.ecx_redportt)(C.calloc(1, C.sizeof_ecx_redportt))
+	if soem.redport == nil {
+		C.soem_context_free(soem.context)
+		return nil, errors.New("error allocating redundant port")
+	}
+
+	if C.ecx_init_redundant(soem.context, soem.redport, cprimary, csecondary) <= 0 {
+		C.free(unsafe.Pointer(soem.redport))
+		C.soem_context_free(soem.context)
+		return nil, fmt.Errorf("error opening interfaces %s and %s", primary, secondary)
+	}
+
+	registerMaster(soem)
 	return soem, nil
 }
 
 func (m *Master) Close() {
-	C.ecx_close(&m.context)
-	C.free(unsafe.Pointer(m.ioMap))
+	unregisterMaster(m)
+	C.ecx_close(m.context)
+	C.soem_context_free(m.context)
+	m.context = nil
+	C.free(unsafe.Pointer(m.redport))
+	m.redport = nil
+	for i, ioMap := range m.ioMaps {
+		C.free(ioMap)
+		m.ioMaps[i] = nil
+	}
+}
+
+// Slave 0 is the master's pseudo-slave used to address all slaves
+func (m *Master) ecSlave(slave uint16) *C.ec_slavet {
+	return &(*[C.EC_MAXSLAVE]C.ec_slavet)(unsafe.Pointer(m.context.slavelist))[slave]
+}
+
+func (m *Master) ecGroup(group uint8) *C.ec_groupt {
+	return &(*[C.EC_MAXGROUP]C.ec_groupt)(unsafe.Pointer(m.context.grouplist))[group]
 }
 
 func (m *Master) ConfigInit() {
-	m.SlaveCount = uint16(C.ecx_config_init(&m.context, 0))
+	m.SlaveCount = uint16(C.ecx_config_init(m.context, 0))
 	m.Slaves = make([]*Slave, m.SlaveCount)
 
 	for i := 0; i < int(m.SlaveCount); i++ {
 		// Do stuff to update the slaves
 		slave := new(Slave)
-		cslave := C.ec_slave[i+1]
+		cslave := m.ecSlave(uint16(i + 1))
 
+		slave.Position = uint16(i + 1)
 		slave.VendorID = uint32(cslave.eep_man)
 		slave.ProductCode = uint32(cslave.eep_id)
 		slave.Revision = uint32(cslave.eep_rev)
@@ -64,57 +192,180 @@ func (m *Master) ConfigInit() {
 		slave.AliasAddress = uint16(cslave.aliasadr)
 		slave.ConfiguredAddress = uint16(cslave.configadr)
 
+		slave.Parent = uint16(cslave.parent)
+		slave.Topology = uint8(cslave.topology)
+		slave.ActivePorts = uint8(cslave.activeports)
+
 		slave.HasDC = cslave.hasdc == 1
 		slave.D = uint8(cslave.hasdc)
+		slave.PropagationDelay = time.Duration(cslave.pdelay)
+
+		slave.MailboxProtocols = MailboxProtocol(cslave.mbx_proto)
+		slave.Group = uint8(cslave.group)
 
 		m.Slaves[i] = slave
 	}
+
+	m.assignPorts()
+	m.installHooks()
 }
 
 func (m *Master) ConfigDC() bool {
-	return C.ecx_configdc(&m.context) == 1
+	hasDC := C.ecx_configdc(m.context) == 1
+	for i, s := range m.Slaves {
+		cslave := m.ecSlave(uint16(i + 1))
+		s.PropagationDelay = time.Duration(cslave.pdelay)
+	}
+	return hasDC
 }
 
 func (m *Master) DCSync0(slave uint16, cycleTime, cycleShift time.Duration) {
 	C.ecx_dcsync0(
-		&m.context,
+		m.context,
 		C.ushort(slave),
 		C.uchar(1),
 		C.uint(cycleTime.Nanoseconds()),
 		C.int(cycleShift.Nanoseconds()))
 }
 
-func (m *Master) ConfigMapWithGroup(group uint8, size uint) {
-	m.ioMap = C.malloc(C.size_t(size))
-	m.ioMapSize = C.ecx_config_map_group(&m.context, m.ioMap, C.uchar(group))
+// Activates SYNC0 and SYNC1. SYNC1 fires cycleTime1 after SYNC0, and the
+// combined cycle is rounded up to a whole number of SYNC0 cycles.
+func (m *Master) DCSync01(slave uint16, cycleTime0, cycleTime1, cycleShift time.Duration) {
+	C.ecx_dcsync01(
+		m.context,
+		C.ushort(slave),
+		C.uchar(1),
+		C.uint(cycleTime0.Nanoseconds()),
+		C.uint(cycleTime1.Nanoseconds()),
+		C.int(cycleShift.Nanoseconds()))
+}
+
+// Deactivates the slave's SYNC0 and SYNC1 outputs
+func (m *Master) DCSyncDisable(slave uint16) {
+	C.ecx_dcsync0(m.context, C.ushort(slave), C.uchar(0), 0, 0)
+}
+
+func checkGroup(group uint8) error {
+	if int(group) >= C.EC_MAXGROUP {
+		return fmt.Errorf("group %d exceeds EC_MAXGROUP (%d)", group, C.EC_MAXGROUP)
+	}
+	return nil
+}
+
+// Assigns a slave to a process data group. This must be done before the
+// group is mapped. Group 0 maps every slave regardless of its assignment,
+// so multiple groups should be numbered from 1.
+//
+// Groups are limited to SOEM's compile time EC_MAXGROUP, which defaults to
+// 2, leaving only group 1 next to group 0 in a stock build. Running e.g. a
+// fast servo group and a slow I/O group side by side needs SOEM rebuilt
+// with EC_MAXGROUP raised in ethercatmain.h. Every group argument is
+// checked against it.
+func (m *Master) SetSlaveGroup(slave uint16, group uint8) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+	if err := checkGroup(group); err != nil {
+		return err
+	}
+
+	m.ecSlave(slave).group = C.uint8(group)
+	m.Slaves[slave-1].Group = group
+	return nil
+}
+
+// Maps the process data of the slaves in group into a newly allocated IO
+// map of size bytes. Each group has its own IO map. If the process data
+// needs more than size bytes, the group is mapped again into a map of the
+// size SOEM reports. Returns the first error from the slaves' PRE_OP to
+// SAFE_OP configuration hooks.
+func (m *Master) ConfigMapWithGroup(group uint8, size uint) error {
+	if err := checkGroup(group); err != nil {
+		return err
+	}
+	inGroup := func(slave uint16) bool {
+		return group == 0 || uint8(m.ecSlave(slave).group) == group
+	}
+	for {
+		m.clearHookErrors(inGroup)
+		C.free(m.ioMaps[group])
+		m.ioMaps[group] = C.calloc(1, C.size_t(size))
+		if m.ioMaps[group] == nil {
+			return fmt.Errorf("error allocating %d byte IO map for group %d", size, group)
+		}
+		m.ioMapSizes[group] = C.ecx_config_map_group(m.context, m.ioMaps[group], C.uchar(group))
+		if uint(m.ioMapSizes[group]) <= size {
+			break
+		}
+		// SOEM only sets up pointers into the IO map while mapping, so a
+		// map that is too small is replaced before any data is exchanged
+		size = uint(m.ioMapSizes[group])
+	}
+	cgroup := m.ecGroup(group)
 
 	for i, s := range m.Slaves {
-		cslave := C.ec_slave[i+1]
+		cslave := m.ecSlave(uint16(i + 1))
+		if group != 0 && uint8(cslave.group) != group {
+			continue
+		}
 
 		pdo := SlavePDO{
-			uint16(cslave.Ibits),
-			uint32(cslave.Ibytes),
-			uint16(cslave.Obits),
-			uint32(cslave.Obytes),
-			cslave.inputs,
-			cslave.outputs}
+			InputBits:      uint16(cslave.Ibits),
+			InputBytes:     uint32(cslave.Ibytes),
+			OutputBits:     uint16(cslave.Obits),
+			OutputBytes:    uint32(cslave.Obytes),
+			InputStartBit:  uint8(cslave.Istartbit),
+			OutputStartBit: uint8(cslave.Ostartbit),
+			InputOffset:    imageOffset(unsafe.Pointer(cgroup.inputs), unsafe.Pointer(cslave.inputs), uint8(cslave.Istartbit)),
+			OutputOffset:   imageOffset(unsafe.Pointer(cgroup.outputs), unsafe.Pointer(cslave.outputs), uint8(cslave.Ostartbit)),
+			inputBuffer:    cslave.inputs,
+			outputBuffer:   cslave.outputs,
+		}
+		pdo.inputs = newProcessImage(unsafe.Pointer(pdo.inputBuffer), pdo.InputStartBit, pdo.InputBits)
+		pdo.outputs = newProcessImage(unsafe.Pointer(pdo.outputBuffer), pdo.OutputStartBit, pdo.OutputBits)
 
 		m.Slaves[i].PDO = &pdo
 		fmt.Println(s)
 	}
+
+	return m.takeHookError(inGroup)
+}
+
+// Bit offset of a slave's process data from the start of its group's image
+func imageOffset(image, data unsafe.Pointer, startBit uint8) uint {
+	if image == nil || data == nil {
+		return 0
+	}
+	return uint(uintptr(data)-uintptr(image))*8 + uint(startBit)
 }
 
-func (m *Master) ConfigMap(size uint) {
-	m.ConfigMapWithGroup(0, size)
+func (m *Master) ConfigMap(size uint) error {
+	return m.ConfigMapWithGroup(0, size)
 }
 
 func (m *Master) ReadState() int {
-	return int(C.ecx_readstate(&m.context))
+	lowest := int(C.ecx_readstate(m.context))
+	m.refreshStates()
+	return lowest
+}
+
+func (m *Master) refreshStates() {
+	for i, s := range m.Slaves {
+		cslave := m.ecSlave(uint16(i + 1))
+		s.State = EtherCATState(cslave.state)
+		s.ALStatusCode = uint16(cslave.ALstatuscode)
+	}
 }
 
 func (m *Master) SetState(state EtherCATState) (uint, error) {
-	C.ec_slave[0].state = C.ushort(state)
-	ret := C.ecx_writestate(&m.context, 0)
+	if state == EC_STATE_OPERATIONAL && m.expectedNetwork != nil {
+		if err := m.ValidateNetwork(m.expectedNetwork); err != nil {
+			return 0, err
+		}
+	}
+
+	m.ecSlave(0).state = C.ushort(state)
+	ret := C.ecx_writestate(m.context, 0)
 	if ret < 0 {
 		switch ret {
 		case EC_NOFRAME:
@@ -127,8 +378,23 @@ func (m *Master) SetState(state EtherCATState) (uint, error) {
 	return uint(ret), nil
 }
 
+// Requests state for a single slave and waits for it to be reached
+func (m *Master) SetSlaveState(slave uint16, state EtherCATState, timeout int) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+
+	m.ecSlave(slave).state = C.ushort(state)
+	if C.ecx_writestate(m.context, C.ushort(slave)) <= 0 {
+		return fmt.Errorf("error requesting state %s for slave %d", state, slave)
+	}
+
+	_, err := m.CheckState(slave, state, timeout)
+	return err
+}
+
 func (m *Master) CheckState(slave uint16, expectedState EtherCATState, timeout int) (EtherCATState, error) {
-	state := EtherCATState(int(C.ecx_statecheck(&m.context,
+	state := EtherCATState(int(C.ecx_statecheck(m.context,
 		C.ushort(slave),
 		C.ushort(expectedState),
 		C.int(timeout))))
@@ -140,31 +406,91 @@ func (m *Master) CheckState(slave uint16, expectedState EtherCATState, timeout i
 	return state, nil
 }
 
-func (m *Master) SendProcessDataWithGroup(group uint8) {
-	C.ecx_send_processdata(&m.context)
+func (m *Master) SendProcessDataWithGroup(group uint8) error {
+	if err := checkGroup(group); err != nil {
+		return err
+	}
+	m.sendProcessData(group)
+	return nil
 }
 
 func (m *Master) SendProcessData() {
-	m.SendProcessDataWithGroup(0)
+	m.sendProcessData(0)
+}
+
+func (m *Master) sendProcessData(group uint8) {
+	C.ecx_send_processdata_group(m.context, C.uchar(group))
 }
 
-func (m *Master) ReceiveProcessDataWithGroup(group uint8, timeout int) uint {
-	return uint(C.ecx_receive_processdata(&m.context, C.int(timeout)))
+func (m *Master) ReceiveProcessDataWithGroup(group uint8, timeout int) (uint, error) {
+	if err := checkGroup(group); err != nil {
+		return 0, err
+	}
+	return m.receiveProcessData(group, timeout), nil
 }
 
 func (m *Master) ReceiveProcessData(timeout int) uint {
-	return m.ReceiveProcessDataWithGroup(0, timeout)
+	return m.receiveProcessData(0, timeout)
+}
+
+func (m *Master) receiveProcessData(group uint8, timeout int) uint {
+	wkc := int64(C.ecx_receive_processdata_group(m.context, C.uchar(group), C.int(timeout)))
+	atomic.StoreInt64(&m.lastWKC[group], wkc)
+	atomic.StoreInt64(&m.dcTime, int64(*m.context.DCtime))
+	m.checkWKC(group, int(wkc))
+	return uint(wkc)
+}
+
+// Recovers a slave that was lost and has come back, re-establishing its
+// configured address
+func (m *Master) RecoverSlave(slave uint16, timeout int) error {
+	if C.ecx_recover_slave(m.context, C.ushort(slave), C.int(timeout)) <= 0 {
+		return fmt.Errorf("error recovering slave %d", slave)
+	}
+	return nil
 }
 
-// func (m *SOEM) RecoverSlave(uint16 slave, int timeout) error {
-// 	if C.ecx_recover_slave(&m.context) <= 0 {
-// 		return errors.New("error recovering slave")
-// 	}
-// 	return nil
-// }
+// Brings a slave that has dropped to a lower state back up to SAFE_OP,
+// re-running its PRE_OP to SAFE_OP configuration
+func (m *Master) ReconfigSlave(slave uint16, timeout int) (EtherCATState, error) {
+	isSlave := func(s uint16) bool {
+		return s == slave
+	}
+	m.clearHookErrors(isSlave)
+	state := EtherCATState(C.ecx_reconfig_slave(m.context, C.ushort(slave), C.int(timeout)))
+	if state == EC_STATE_NONE {
+		return state, fmt.Errorf("error reconfiguring slave %d", slave)
+	}
+	return state, m.takeHookError(isSlave)
+}
+
+// Working counter of a complete exchange with every slave in the group
+func (m *Master) ExpectedWKC(group uint8) (int, error) {
+	if err := checkGroup(group); err != nil {
+		return 0, err
+	}
+	return m.expectedWKC(group), nil
+}
+
+func (m *Master) expectedWKC(group uint8) int {
+	cgroup := m.ecGroup(group)
+	return int(cgroup.outputsWKC)*2 + int(cgroup.inputsWKC)
+}
+
+// Working counter returned by the last ReceiveProcessDataWithGroup
+func (m *Master) LastWKC(group uint8) (int, error) {
+	if err := checkGroup(group); err != nil {
+		return 0, err
+	}
+	return m.lastWKCOf(group), nil
+}
+
+func (m *Master) lastWKCOf(group uint8) int {
+	return int(atomic.LoadInt64(&m.lastWKC[group]))
+}
 
 func (m *Master) isError() bool {
-	return int(C.ecx_iserror(&m.context)) > 0
+	return int(C.ecx_iserror(m.context)) > 0
 }
 
 func marshalSlave(cslave C.ec_slavet) *Slave {
@@ -0,0 +1,239 @@
+package soem
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A slave expected at a position in the network
+type ExpectedSlave struct {
+	Name        string `json:"name"`
+	VendorID    uint32 `json:"vendorId"`
+	ProductCode uint32 `json:"productCode"`
+	// 0 accepts any revision
+	Revision uint32 `json:"revision,omitempty"`
+	// 0 accepts any alias
+	Alias uint16 `json:"alias,omitempty"`
+}
+
+func (e *ExpectedSlave) String() string {
+	return fmt.Sprintf("%s (0x%08x:0x%08x rev 0x%08x)", e.Name, e.VendorID, e.ProductCode, e.Revision)
+}
+
+// The slaves a machine is built with, in network order
+type NetworkConfig struct {
+	Slaves []ExpectedSlave `json:"slaves"`
+}
+
+// Loads a network configuration from a JSON file of the form
+// {"slaves": [{"name": "EK1100", "vendorId": 2, "productCode": 72100946}]}
+func LoadNetworkConfig(path string) (*NetworkConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := new(NetworkConfig)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing network configuration %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+type MismatchKind uint8
+
+const (
+	SlaveMissing MismatchKind = iota
+	SlaveExtra
+	SlaveReordered
+	SlaveWrongRevision
+	SlaveWrongAlias
+)
+
+func (k MismatchKind) String() string {
+	switch k {
+	case SlaveMissing:
+		return "missing"
+	case SlaveExtra:
+		return "extra"
+	case SlaveReordered:
+		return "reordered"
+	case SlaveWrongRevision:
+		return "wrong revision"
+	case SlaveWrongAlias:
+		return "wrong alias"
+	default:
+		return fmt.Sprintf("%d", int(k))
+	}
+}
+
+type NetworkMismatch struct {
+	Kind MismatchKind
+	// Position the slave was expected at, 0 for extra slaves
+	ExpectedPosition uint16
+	// Position the slave was found at, 0 for missing slaves
+	FoundPosition uint16
+	Expected      *ExpectedSlave
+	Found         *Slave
+}
+
+func (n NetworkMismatch) String() string {
+	switch n.Kind {
+	case SlaveMissing:
+		return fmt.Sprintf("missing %s at position %d", n.Expected, n.ExpectedPosition)
+	case SlaveExtra:
+		return fmt.Sprintf("extra %s (0x%08x:0x%08x) at position %d",
+			n.Found.Name, n.Found.VendorID, n.Found.ProductCode, n.FoundPosition)
+	case SlaveReordered:
+		return fmt.Sprintf("%s expected at position %d found at position %d",
+			n.Expected.Name, n.ExpectedPosition, n.FoundPosition)
+	case SlaveWrongRevision:
+		return fmt.Sprintf("%s at position %d has revision 0x%08x, expected 0x%08x",
+			n.Expected.Name, n.FoundPosition, n.Found.Revision, n.Expected.Revision)
+	case SlaveWrongAlias:
+		return fmt.Sprintf("%s at position %d has alias %d, expected %d",
+			n.Expected.Name, n.FoundPosition, n.Found.AliasAddress, n.Expected.Alias)
+	default:
+		return n.Kind.String()
+	}
+}
+
+// Returned when the discovered network does not match the expected one
+type NetworkMismatchError struct {
+	Mismatches []NetworkMismatch
+}
+
+func (e *NetworkMismatchError) Error() string {
+	lines := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		lines[i] = "  " + m.String()
+	}
+	return "network does not match configuration:\n" + strings.Join(lines, "\n")
+}
+
+func (e *ExpectedSlave) matches(s *Slave) bool {
+	return e.VendorID == s.VendorID && e.ProductCode == s.ProductCode
+}
+
+// Compares the slaves found by ConfigInit with cfg. Slaves are aligned on
+// vendor ID and product code, then checked for revision and alias.
+func (m *Master) ValidateNetwork(cfg *NetworkConfig) error {
+	expected := cfg.Slaves
+	found := m.Slaves
+
+	// longest common subsequence of device identities
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(found)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(found) - 1; j >= 0; j-- {
+			if expected[i].matches(found[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var mismatches []NetworkMismatch
+	var unmatchedExpected, unmatchedFound []int
+	i, j := 0, 0
+	for i < len(expected) && j < len(found) {
+		switch {
+		case expected[i].matches(found[j]):
+			mismatches = append(mismatches, checkSlave(&expected[i], uint16(i+1), found[j])...)
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			unmatchedExpected = append(unmatchedExpected, i)
+			i++
+		default:
+			unmatchedFound = append(unmatchedFound, j)
+			j++
+		}
+	}
+	for ; i < len(expected); i++ {
+		unmatchedExpected = append(unmatchedExpected, i)
+	}
+	for ; j < len(found); j++ {
+		unmatchedFound = append(unmatchedFound, j)
+	}
+
+	// slaves left over on both sides with the same identity have been moved
+	for _, i := range unmatchedExpected {
+		e := &expected[i]
+		moved := -1
+		for k, j := range unmatchedFound {
+			if e.matches(found[j]) {
+				moved = k
+				break
+			}
+		}
+
+		if moved < 0 {
+			mismatches = append(mismatches, NetworkMismatch{
+				Kind:             SlaveMissing,
+				ExpectedPosition: uint16(i + 1),
+				Expected:         e,
+			})
+			continue
+		}
+
+		j := unmatchedFound[moved]
+		unmatchedFound = append(unmatchedFound[:moved], unmatchedFound[moved+1:]...)
+		mismatches = append(mismatches, NetworkMismatch{
+			Kind:             SlaveReordered,
+			ExpectedPosition: uint16(i + 1),
+			FoundPosition:    found[j].Position,
+			Expected:         e,
+			Found:            found[j],
+		})
+	}
+
+	for _, j := range unmatchedFound {
+		mismatches = append(mismatches, NetworkMismatch{
+			Kind:          SlaveExtra,
+			FoundPosition: found[j].Position,
+			Found:         found[j],
+		})
+	}
+
+	if len(mismatches) > 0 {
+		return &NetworkMismatchError{mismatches}
+	}
+	return nil
+}
+
+func checkSlave(e *ExpectedSlave, position uint16, s *Slave) []NetworkMismatch {
+	var mismatches []NetworkMismatch
+	if e.Revision != 0 && e.Revision != s.Revision {
+		mismatches = append(mismatches, NetworkMismatch{
+			Kind:             SlaveWrongRevision,
+			ExpectedPosition: position,
+			FoundPosition:    s.Position,
+			Expected:         e,
+			Found:            s,
+		})
+	}
+	if e.Alias != 0 && e.Alias != s.AliasAddress {
+		mismatches = append(mismatches, NetworkMismatch{
+			Kind:             SlaveWrongAlias,
+			ExpectedPosition: position,
+			FoundPosition:    s.Position,
+			Expected:         e,
+			Found:            s,
+		})
+	}
+	return mismatches
+}
+
+// Sets the network the machine is built with. SetState refuses to request
+// OP while the discovered network does not match it.
+func (m *Master) SetExpectedNetwork(cfg *NetworkConfig) {
+	m.expectedNetwork = cfg
+}
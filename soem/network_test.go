@@ -0,0 +1,147 @@
+package soem
+
+import (
+	"errors"
+	"testing"
+)
+
+var (
+	ek1100 = ExpectedSlave{Name: "EK1100", VendorID: 2, ProductCode: 0x044c2c52}
+	el1008 = ExpectedSlave{Name: "EL1008", VendorID: 2, ProductCode: 0x03f03052}
+	el2008 = ExpectedSlave{Name: "EL2008", VendorID: 2, ProductCode: 0x07d83052}
+	el3102 = ExpectedSlave{Name: "EL3102", VendorID: 2, ProductCode: 0x0c1e3052}
+	el9011 = ExpectedSlave{Name: "EL9011", VendorID: 2, ProductCode: 0x23333052}
+)
+
+// Builds the slaves ConfigInit would have found, numbered from position 1
+func foundSlaves(expected ...ExpectedSlave) []*Slave {
+	slaves := make([]*Slave, len(expected))
+	for i, e := range expected {
+		slaves[i] = &Slave{
+			Position:     uint16(i + 1),
+			Name:         e.Name,
+			VendorID:     e.VendorID,
+			ProductCode:  e.ProductCode,
+			Revision:     e.Revision,
+			AliasAddress: e.Alias,
+		}
+	}
+	return slaves
+}
+
+func TestValidateNetwork(t *testing.T) {
+	type mismatch struct {
+		kind     MismatchKind
+		expected uint16
+		found    uint16
+	}
+
+	el1008rev := el1008
+	el1008rev.Revision = 0x00110000
+	el1008alias := el1008
+	el1008alias.Alias = 1001
+
+	tests := []struct {
+		name     string
+		expected []ExpectedSlave
+		found    []*Slave
+		want     []mismatch
+	}{
+		{
+			name:     "match",
+			expected: []ExpectedSlave{ek1100, el1008, el2008, el3102},
+			found:    foundSlaves(ek1100, el1008, el2008, el3102),
+		},
+		{
+			name:     "empty",
+			expected: nil,
+			found:    nil,
+		},
+		{
+			name:     "swapped terminals",
+			expected: []ExpectedSlave{ek1100, el1008, el2008, el3102},
+			found:    foundSlaves(ek1100, el2008, el1008, el3102),
+			want:     []mismatch{{SlaveReordered, 2, 3}},
+		},
+		{
+			name:     "terminal moved to the end",
+			expected: []ExpectedSlave{ek1100, el1008, el2008, el3102},
+			found:    foundSlaves(ek1100, el2008, el3102, el1008),
+			want:     []mismatch{{SlaveReordered, 2, 4}},
+		},
+		{
+			name:     "missing terminal",
+			expected: []ExpectedSlave{ek1100, el1008, el2008, el3102},
+			found:    foundSlaves(ek1100, el1008, el3102),
+			want:     []mismatch{{SlaveMissing, 3, 0}},
+		},
+		{
+			name:     "network cut after coupler",
+			expected: []ExpectedSlave{ek1100, el1008, el2008},
+			found:    foundSlaves(ek1100),
+			want:     []mismatch{{SlaveMissing, 2, 0}, {SlaveMissing, 3, 0}},
+		},
+		{
+			name:     "extra terminal",
+			expected: []ExpectedSlave{ek1100, el1008, el2008},
+			found:    foundSlaves(ek1100, el1008, el9011, el2008),
+			want:     []mismatch{{SlaveExtra, 0, 3}},
+		},
+		{
+			name:     "wrong terminal",
+			expected: []ExpectedSlave{ek1100, el1008, el2008},
+			found:    foundSlaves(ek1100, el3102, el2008),
+			want:     []mismatch{{SlaveMissing, 2, 0}, {SlaveExtra, 0, 2}},
+		},
+		{
+			name:     "any revision and alias",
+			expected: []ExpectedSlave{ek1100, el1008},
+			found:    foundSlaves(ek1100, el1008alias),
+		},
+		{
+			name:     "wrong revision",
+			expected: []ExpectedSlave{ek1100, el1008rev},
+			found:    foundSlaves(ek1100, el1008),
+			want:     []mismatch{{SlaveWrongRevision, 2, 2}},
+		},
+		{
+			name:     "wrong alias",
+			expected: []ExpectedSlave{ek1100, el1008alias},
+			found:    foundSlaves(ek1100, el1008),
+			want:     []mismatch{{SlaveWrongAlias, 2, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		m := &Master{Slaves: tt.found}
+		err := m.ValidateNetwork(&NetworkConfig{Slaves: tt.expected})
+
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+
+		var mismatchErr *NetworkMismatchError
+		if !errors.As(err, &mismatchErr) {
+			t.Errorf("%s: error %v, want NetworkMismatchError", tt.name, err)
+			continue
+		}
+
+		got := make([]mismatch, len(mismatchErr.Mismatches))
+		for i, n := range mismatchErr.Mismatches {
+			got[i] = mismatch{n.Kind, n.ExpectedPosition, n.FoundPosition}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: mismatches %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: mismatches %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
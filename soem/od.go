@@ -0,0 +1,184 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+type ObjectCode uint8
+
+const (
+	OTYPE_VAR    ObjectCode = 0x07
+	OTYPE_ARRAY  ObjectCode = 0x08
+	OTYPE_RECORD ObjectCode = 0x09
+)
+
+func (c ObjectCode) String() string {
+	switch c {
+	case OTYPE_VAR:
+		return "VAR"
+	case OTYPE_ARRAY:
+		return "ARRAY"
+	case OTYPE_RECORD:
+		return "RECORD"
+	default:
+		return fmt.Sprintf("0x%02x", uint8(c))
+	}
+}
+
+// Object entry access rights as reported by the SDO Information service
+type ObjectAccess uint16
+
+const (
+	ACCESS_READ_PREOP   ObjectAccess = 0x0001
+	ACCESS_READ_SAFEOP  ObjectAccess = 0x0002
+	ACCESS_READ_OP      ObjectAccess = 0x0004
+	ACCESS_WRITE_PREOP  ObjectAccess = 0x0008
+	ACCESS_WRITE_SAFEOP ObjectAccess = 0x0010
+	ACCESS_WRITE_OP     ObjectAccess = 0x0020
+	ACCESS_RXPDO_MAP    ObjectAccess = 0x0040
+	ACCESS_TXPDO_MAP    ObjectAccess = 0x0080
+	ACCESS_BACKUP       ObjectAccess = 0x0100
+	ACCESS_SETTINGS     ObjectAccess = 0x0200
+
+	ACCESS_READ  = ACCESS_READ_PREOP | ACCESS_READ_SAFEOP | ACCESS_READ_OP
+	ACCESS_WRITE = ACCESS_WRITE_PREOP | ACCESS_WRITE_SAFEOP | ACCESS_WRITE_OP
+)
+
+func (a ObjectAccess) Readable() bool {
+	return a&ACCESS_READ != 0
+}
+
+func (a ObjectAccess) Writable() bool {
+	return a&ACCESS_WRITE != 0
+}
+
+func (a ObjectAccess) PDOMappable() bool {
+	return a&(ACCESS_RXPDO_MAP|ACCESS_TXPDO_MAP) != 0
+}
+
+// Short form as used by SOEM's slaveinfo, e.g. "RW" or "RO"
+func (a ObjectAccess) String() string {
+	return stringSelect(a.Readable(), "R", "") + stringSelect(a.Writable(), "W", "O")
+}
+
+type Object struct {
+	Index       uint16
+	Name        string
+	DataType    EtherCATDataType
+	ObjectCode  ObjectCode
+	MaxSubIndex uint8
+	Entries     []ObjectEntry
+}
+
+type ObjectEntry struct {
+	SubIndex  uint8
+	Name      string
+	DataType  EtherCATDataType
+	BitLength uint16
+	Access    ObjectAccess
+}
+
+// Walks the slave's object dictionary using the CoE SDO Information
+// service. Sub-indices that the slave does not describe are omitted.
+func (m *Master) ReadObjectDictionary(slave uint16) ([]*Object, error) {
+	odList, oeList := newObjectLists()
+	defer C.free(unsafe.Pointer(odList))
+	defer C.free(unsafe.Pointer(oeList))
+
+	if C.ecx_readODlist(m.context, C.ushort(slave), odList) <= 0 {
+		return nil, fmt.Errorf("error reading object list of slave %d", slave)
+	}
+
+	objects := make([]*Object, odList.Entries)
+	for i := range objects {
+		object, err := m.readObject(slave, odList, oeList, uint16(i))
+		if err != nil {
+			return nil, err
+		}
+		objects[i] = object
+	}
+
+	return objects, nil
+}
+
+// Reads the description and entries of a single object
+func (m *Master) ReadObject(slave, index uint16) (*Object, error) {
+	odList, oeList := newObjectLists()
+	defer C.free(unsafe.Pointer(odList))
+	defer C.free(unsafe.Pointer(oeList))
+
+	odList.Slave = C.uint16(slave)
+	odList.Entries = 1
+	odList.Index[0] = C.uint16(index)
+
+	return m.readObject(slave, odList, oeList, 0)
+}
+
+func newObjectLists() (*C.ec_ODlistt, *C.ec_OElistt) {
+	return (*C.ec_ODlistt)(C.calloc(1, C.sizeof_ec_ODlistt)),
+		(*C.ec_OElistt)(C.calloc(1, C.sizeof_ec_OElistt))
+}
+
+func (m *Master) readObject(slave uint16, odList *C.ec_ODlistt, oeList *C.ec_OElistt, item uint16) (*Object, error) {
+	if C.ecx_readODdescription(m.context, C.ushort(item), odList) <= 0 {
+		return nil, fmt.Errorf("error reading description of object 0x%04x on slave %d", uint16(odList.Index[item]), slave)
+	}
+
+	object := &Object{
+		Index:       uint16(odList.Index[item]),
+		Name:        C.GoString(&odList.Name[item][0]),
+		DataType:    EtherCATDataType(odList.DataType[item]),
+		ObjectCode:  ObjectCode(odList.ObjectCode[item]),
+		MaxSubIndex: uint8(odList.MaxSub[item]),
+	}
+
+	*oeList = C.ec_OElistt{}
+	if C.ecx_readOE(m.context, C.ushort(item), odList, oeList) <= 0 {
+		return nil, fmt.Errorf("error reading entries of object 0x%04x on slave %d", object.Index, slave)
+	}
+
+	for j := 0; j <= int(object.MaxSubIndex) && j < C.EC_MAXOELIST; j++ {
+		if oeList.DataType[j] == 0 && oeList.BitLength[j] == 0 {
+			continue
+		}
+
+		object.Entries = append(object.Entries, ObjectEntry{
+			SubIndex:  uint8(j),
+			Name:      C.GoString(&oeList.Name[j][0]),
+			DataType:  EtherCATDataType(oeList.DataType[j]),
+			BitLength: uint16(oeList.BitLength[j]),
+			Access:    ObjectAccess(oeList.ObjAccess[j]),
+		})
+	}
+
+	return object, nil
+}
+
+// Returns the entry with the given sub-index, or nil if the object has none
+func (o *Object) Entry(subIndex uint8) *ObjectEntry {
+	for i := range o.Entries {
+		if o.Entries[i].SubIndex == subIndex {
+			return &o.Entries[i]
+		}
+	}
+	return nil
+}
+
+func (o *Object) String() string {
+	s := fmt.Sprintf("0x%04x %s %s %s\n", o.Index, o.ObjectCode, o.DataType, o.Name)
+	for _, e := range o.Entries {
+		s += fmt.Sprintf("  0x%04x:%02x %s %s %d bits %s\n",
+			o.Index, e.SubIndex, e.Access, e.DataType, e.BitLength, e.Name)
+	}
+	return s
+}
@@ -0,0 +1,500 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+*/
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+type PDODirection uint8
+
+const (
+	// Outputs, master to slave
+	RxPDO PDODirection = iota
+	// Inputs, slave to master
+	TxPDO
+)
+
+func (d PDODirection) String() string {
+	if d == TxPDO {
+		return "TxPDO"
+	}
+	return "RxPDO"
+}
+
+// A PDO entry mapped into a slave's process data
+type Variable struct {
+	Slave     uint16
+	Direction PDODirection
+	PDOIndex  uint16
+	PDOName   string
+	Index     uint16
+	SubIndex  uint8
+	Name      string
+	DataType  EtherCATDataType
+	// Offset from the start of the slave's inputs or outputs
+	BitOffset uint
+	BitLength uint
+
+	image *ProcessImage
+}
+
+// PDO name and entry name joined as "Channel 3.Input"
+func (v *Variable) FullName() string {
+	if v.PDOName == "" {
+		return v.Name
+	}
+	return v.PDOName + "." + v.Name
+}
+
+func (v *Variable) String() string {
+	return fmt.Sprintf("%s 0x%04x:%02x %s %s bit %d+%d",
+		v.Direction, v.Index, v.SubIndex, v.FullName(), v.DataType, v.BitOffset, v.BitLength)
+}
+
+func (v *Variable) Bits() (uint64, error) {
+	if v.image == nil {
+		return 0, fmt.Errorf("variable %s is not mapped", v.FullName())
+	}
+	return v.image.Bitfield(v.BitOffset, v.BitLength)
+}
+
+func (v *Variable) SetBits(value uint64) error {
+	if v.image == nil {
+		return fmt.Errorf("variable %s is not mapped", v.FullName())
+	}
+	return v.image.SetBitfield(v.BitOffset, v.BitLength, value)
+}
+
+func (v *Variable) Bool() (bool, error) {
+	b, err := v.Bits()
+	return b != 0, err
+}
+
+// Decodes the variable according to its data type
+func (v *Variable) Value() (interface{}, error) {
+	b, err := v.Bits()
+	if err != nil {
+		return nil, err
+	}
+
+	var data [8]byte
+	binary.LittleEndian.PutUint64(data[:], b)
+	return DecodeValue(v.DataType, data[:])
+}
+
+// Encodes value according to the variable's data type and writes it
+func (v *Variable) Set(value interface{}) error {
+	data, err := EncodeValue(v.DataType, value)
+	if err != nil {
+		return err
+	}
+
+	var buf [8]byte
+	copy(buf[:], data)
+	return v.SetBits(binary.LittleEndian.Uint64(buf[:]))
+}
+
+// An object entry to map into a PDO. Index 0 maps a gap of BitLength bits.
+type PDOEntry struct {
+	Index     uint16
+	SubIndex  uint8
+	BitLength uint8
+}
+
+// A PDO to assign, with the entries to map into it. A PDO without entries
+// is assigned with the mapping the slave already has, as needed for slaves
+// with fixed PDO mappings.
+type PDOConfig struct {
+	Index   uint16
+	Entries []PDOEntry
+}
+
+// PDOs to assign to the outputs (0x1C12) and inputs (0x1C13) of a slave
+type PDOMapping struct {
+	RxPDOs []PDOConfig
+	TxPDOs []PDOConfig
+}
+
+// Replaces the slave's PDO assignment and mapping. The mapping is written
+// over CoE in PRE_OP each time the slave is configured for SAFE_OP, so it
+// must be set before ConfigMap and is reapplied when the slave is recovered.
+func (m *Master) SetPDOMapping(slave uint16, mapping PDOMapping) {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+
+	if m.pdoMappings == nil {
+		m.pdoMappings = make(map[uint16]PDOMapping)
+	}
+	m.pdoMappings[slave] = mapping
+}
+
+func (m *Master) ClearPDOMapping(slave uint16) {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+
+	delete(m.pdoMappings, slave)
+}
+
+func (m *Master) writePDOMapping(slave uint16, mapping PDOMapping) error {
+	for _, assign := range []struct {
+		index uint16
+		pdos  []PDOConfig
+	}{{0x1C12, mapping.RxPDOs}, {0x1C13, mapping.TxPDOs}} {
+		// the assignment must be cleared before mapped PDOs can be changed
+		if err := m.SDOWriteValue(slave, assign.index, 0, ECT_UNSIGNED8, 0); err != nil {
+			return err
+		}
+
+		for i, pdo := range assign.pdos {
+			if len(pdo.Entries) > 0 {
+				if err := m.writePDO(slave, pdo); err != nil {
+					return err
+				}
+			}
+
+			if err := m.SDOWriteValue(slave, assign.index, uint8(i+1), ECT_UNSIGNED16, pdo.Index); err != nil {
+				return err
+			}
+		}
+
+		if err := m.SDOWriteValue(slave, assign.index, 0, ECT_UNSIGNED8, len(assign.pdos)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Master) writePDO(slave uint16, pdo PDOConfig) error {
+	if err := m.SDOWriteValue(slave, pdo.Index, 0, ECT_UNSIGNED8, 0); err != nil {
+		return err
+	}
+
+	for i, e := range pdo.Entries {
+		entry := uint32(e.Index)<<16 | uint32(e.SubIndex)<<8 | uint32(e.BitLength)
+		if err := m.SDOWriteValue(slave, pdo.Index, uint8(i+1), ECT_UNSIGNED32, entry); err != nil {
+			return err
+		}
+	}
+
+	return m.SDOWriteValue(slave, pdo.Index, 0, ECT_UNSIGNED8, len(pdo.Entries))
+}
+
+// Reads the PDO mapping of every mapped slave and builds their variables
+func (m *Master) ReadPDOMappings() error {
+	for i := range m.Slaves {
+		if m.Slaves[i].PDO == nil {
+			continue
+		}
+		if _, err := m.ReadPDOMapping(uint16(i + 1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads the PDO assignment and mapping of a slave, using CoE objects
+// 0x1C12/0x1C13 where available, the SoE AT and MDT configuration for SoE
+// drives and the SII PDO categories otherwise. Must be called after the
+// slave's group has been mapped.
+func (m *Master) ReadPDOMapping(slave uint16) ([]*Variable, error) {
+	if slave < 1 || slave > m.SlaveCount {
+		return nil, fmt.Errorf("no slave %d", slave)
+	}
+	s := m.Slaves[slave-1]
+	if s.PDO == nil {
+		return nil, fmt.Errorf("slave %d is not mapped", slave)
+	}
+
+	var vars []*Variable
+	var err error
+	if s.MailboxProtocols&ECT_MBXPROT_COE != 0 {
+		vars, err = m.readCoEPDOMapping(slave)
+	} else if s.MailboxProtocols&ECT_MBXPROT_SOE != 0 {
+		vars, err = m.readSoEPDOMapping(slave)
+	} else {
+		vars, err = m.readSIIPDOMapping(slave)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return vars, m.SetVariables(slave, vars)
+}
+
+// Replaces the variables of a mapped slave, for mappings known from a
+// configuration file rather than read from the slave. Variables are bound
+// to the slave's inputs or outputs by their direction.
+func (m *Master) SetVariables(slave uint16, vars []*Variable) error {
+	if slave < 1 || slave > m.SlaveCount || m.Slaves[slave-1].PDO == nil {
+		return fmt.Errorf("slave %d is not mapped", slave)
+	}
+	s := m.Slaves[slave-1]
+
+	for _, v := range vars {
+		image := s.PDO.outputs
+		if v.Direction == TxPDO {
+			image = s.PDO.inputs
+		}
+		if v.BitOffset > image.Bits() || v.BitLength > image.Bits()-v.BitOffset {
+			return fmt.Errorf("variable %s exceeds the %d bit %s of slave %d",
+				v.FullName(), image.Bits(), v.Direction, slave)
+		}
+		v.Slave = slave
+		v.image = image
+	}
+	s.PDO.Variables = vars
+
+	return nil
+}
+
+func (m *Master) readCoEPDOMapping(slave uint16) ([]*Variable, error) {
+	var vars []*Variable
+
+	for _, assign := range []struct {
+		index     uint16
+		direction PDODirection
+	}{{0x1C12, RxPDO}, {0x1C13, TxPDO}} {
+		count, err := m.SDOReadValue(slave, assign.index, 0, ECT_UNSIGNED8)
+		if err != nil {
+			return nil, err
+		}
+
+		// subindex 255 is reserved, so at most 254 PDOs are assigned
+		if count.(uint8) > 254 {
+			return nil, fmt.Errorf("slave %d reports %d PDOs assigned in 0x%04x", slave, count.(uint8), assign.index)
+		}
+
+		bitOffset := uint(0)
+		for i := 1; i <= int(count.(uint8)); i++ {
+			pdoIndex, err := m.SDOReadValue(slave, assign.index, uint8(i), ECT_UNSIGNED16)
+			if err != nil {
+				return nil, err
+			}
+
+			pdoVars, err := m.readCoEPDO(slave, pdoIndex.(uint16), assign.direction, &bitOffset)
+			if err != nil {
+				return nil, err
+			}
+			vars = append(vars, pdoVars...)
+		}
+	}
+
+	return vars, nil
+}
+
+func (m *Master) readCoEPDO(slave, pdoIndex uint16, direction PDODirection, bitOffset *uint) ([]*Variable, error) {
+	count, err := m.SDOReadValue(slave, pdoIndex, 0, ECT_UNSIGNED8)
+	if err != nil {
+		return nil, err
+	}
+	if count.(uint8) > 254 {
+		return nil, fmt.Errorf("slave %d reports %d entries mapped in PDO 0x%04x", slave, count.(uint8), pdoIndex)
+	}
+
+	// names and types are optional, slaves without the SDO Information
+	// service get generated names
+	pdoName := fmt.Sprintf("0x%04x", pdoIndex)
+	if pdo, err := m.ReadObject(slave, pdoIndex); err == nil {
+		pdoName = pdo.Name
+	}
+
+	var vars []*Variable
+	objects := map[uint16]*Object{}
+	for i := 1; i <= int(count.(uint8)); i++ {
+		entry, err := m.SDOReadValue(slave, pdoIndex, uint8(i), ECT_UNSIGNED32)
+		if err != nil {
+			return nil, err
+		}
+
+		v := &Variable{
+			Slave:     slave,
+			Direction: direction,
+			PDOIndex:  pdoIndex,
+			PDOName:   pdoName,
+			Index:     uint16(entry.(uint32) >> 16),
+			SubIndex:  uint8(entry.(uint32) >> 8),
+			BitOffset: *bitOffset,
+			BitLength: uint(entry.(uint32) & 0xff),
+		}
+		*bitOffset += v.BitLength
+
+		// padding
+		if v.Index == 0 {
+			continue
+		}
+
+		v.Name = fmt.Sprintf("0x%04x:%02x", v.Index, v.SubIndex)
+		v.DataType = dataTypeForBitLength(v.BitLength)
+
+		object, ok := objects[v.Index]
+		if !ok {
+			object, _ = m.ReadObject(slave, v.Index)
+			objects[v.Index] = object
+		}
+		if object != nil {
+			if e := object.Entry(v.SubIndex); e != nil {
+				v.Name = e.Name
+				v.DataType = e.DataType
+			} else if object.ObjectCode == OTYPE_VAR {
+				v.Name = object.Name
+				v.DataType = object.DataType
+			}
+		}
+
+		vars = append(vars, v)
+	}
+
+	return vars, nil
+}
+
+// Reads the TxPDO and RxPDO categories from the slave information
+// interface. Only PDOs assigned to a sync manager are mapped.
+func (m *Master) readSIIPDOMapping(slave uint16) ([]*Variable, error) {
+	var vars []*Variable
+
+	for _, category := range []struct {
+		category  SIICategory
+		direction PDODirection
+	}{{ECT_SII_RXPDO, RxPDO}, {ECT_SII_TXPDO, TxPDO}} {
+		// ecx_siifind returns the byte address of the category length word
+		address := uint16(C.ecx_siifind(m.context, C.uint16(slave), C.uint16(category.category)))
+		if address == 0 {
+			continue
+		}
+
+		end := address + 2 + m.siiWord(slave, address)*2
+		address += 2
+		bitOffset := uint(0)
+
+		for address+8 <= end {
+			pdoIndex := m.siiWord(slave, address)
+			entries := m.siiByte(slave, address+2)
+			syncManager := m.siiByte(slave, address+3)
+			pdoName := m.siiString(slave, m.siiByte(slave, address+5))
+			address += 8
+
+			for i := uint8(0); i < entries; i++ {
+				v := &Variable{
+					Slave:     slave,
+					Direction: category.direction,
+					PDOIndex:  pdoIndex,
+					PDOName:   pdoName,
+					Index:     m.siiWord(slave, address),
+					SubIndex:  m.siiByte(slave, address+2),
+					Name:      m.siiString(slave, m.siiByte(slave, address+3)),
+					DataType:  EtherCATDataType(m.siiByte(slave, address+4)),
+					BitOffset: bitOffset,
+					BitLength: uint(m.siiByte(slave, address+5)),
+				}
+				address += 8
+
+				if syncManager >= C.EC_MAXSM {
+					continue
+				}
+				bitOffset += v.BitLength
+
+				if v.Index == 0 {
+					continue
+				}
+				if v.Name == "" {
+					v.Name = fmt.Sprintf("0x%04x:%02x", v.Index, v.SubIndex)
+				}
+				if v.DataType == 0 {
+					v.DataType = dataTypeForBitLength(v.BitLength)
+				}
+				vars = append(vars, v)
+			}
+		}
+	}
+
+	return vars, nil
+}
+
+func (m *Master) siiByte(slave, address uint16) uint8 {
+	return uint8(C.ecx_siigetbyte(m.context, C.uint16(slave), C.uint16(address)))
+}
+
+func (m *Master) siiWord(slave, address uint16) uint16 {
+	return uint16(m.siiByte(slave, address)) | uint16(m.siiByte(slave, address+1))<<8
+}
+
+func (m *Master) siiString(slave uint16, index uint8) string {
+	if index == 0 {
+		return ""
+	}
+
+	var str [C.EC_MAXNAME + 1]C.char
+	C.ecx_siistring(m.context, &str[0], C.uint16(slave), C.uint16(index))
+	return C.GoString(&str[0])
+}
+
+func dataTypeForBitLength(bits uint) EtherCATDataType {
+	switch {
+	case bits == 1:
+		return ECT_BOOLEAN
+	case bits < 8:
+		return ECT_BIT1 + EtherCATDataType(bits-1)
+	case bits == 8:
+		return ECT_UNSIGNED8
+	case bits == 16:
+		return ECT_UNSIGNED16
+	case bits == 32:
+		return ECT_UNSIGNED32
+	case bits == 64:
+		return ECT_UNSIGNED64
+	default:
+		return ECT_OCTET_STRING
+	}
+}
+
+// Looks up a variable by slave name and variable name. The variable name is
+// matched against the full name ("Channel 3.Input"), then the PDO name
+// ("Channel 3") and finally the entry name, and must be unique at the first
+// level that matches. If several slaves share a name the first is used.
+func (m *Master) Var(slaveName, name string) (*Variable, error) {
+	for i, s := range m.Slaves {
+		if s.Name == slaveName {
+			return m.VarAt(uint16(i+1), name)
+		}
+	}
+	return nil, fmt.Errorf("no slave named %s", slaveName)
+}
+
+func (m *Master) VarAt(slave uint16, name string) (*Variable, error) {
+	if slave < 1 || slave > m.SlaveCount || m.Slaves[slave-1].PDO == nil {
+		return nil, fmt.Errorf("slave %d is not mapped", slave)
+	}
+	vars := m.Slaves[slave-1].PDO.Variables
+
+	for _, key := range []func(*Variable) string{
+		(*Variable).FullName,
+		func(v *Variable) string { return v.PDOName },
+		func(v *Variable) string { return v.Name },
+	} {
+		var found *Variable
+		for _, v := range vars {
+			if key(v) != name {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("variable %s is ambiguous on slave %d", name, slave)
+			}
+			found = v
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no variable %s on slave %d", name, slave)
+}
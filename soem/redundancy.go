@@ -0,0 +1,169 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+// Sends a broadcast read and checks the source MAC of the frame the primary
+// port received for it. SOEM marks frames sent from the primary port with
+// RX_PRIM and the dummy frames sent from the secondary port with RX_SEC.
+// Returns 0 if the primary frame returned to the primary port, 1 if the
+// secondary dummy did, and -1 if nothing did.
+static int soem_primary_return(ecx_portt *port, int timeout)
+{
+	uint16 data = 0;
+	uint8 idx;
+	int primrx;
+
+	idx = ecx_getindex(port);
+	port->rxsa[idx] = 0;
+	ecx_setupdatagram(port, &(port->txbuf[idx]), EC_CMD_BRD, idx, 0x0000, ECT_REG_TYPE, sizeof(data), &data);
+	ecx_srconfirm(port, idx, timeout);
+	primrx = port->rxsa[idx];
+	ecx_setbufstat(port, idx, EC_BUF_EMPTY);
+
+	if (primrx == RX_PRIM)
+		return 0;
+	if (primrx == RX_SEC)
+		return 1;
+	return -1;
+}
+
+*/
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+type MasterPort uint8
+
+const (
+	PrimaryPort MasterPort = iota
+	SecondaryPort
+	// Frame was lost
+	NoPort MasterPort = 0xFF
+)
+
+func (p MasterPort) String() string {
+	switch p {
+	case PrimaryPort:
+		return "primary"
+	case SecondaryPort:
+		return "secondary"
+	default:
+		return "none"
+	}
+}
+
+// A slave port that had a link when the network was configured but no
+// longer communicates
+type LinkBreak struct {
+	Slave uint16
+	Port  uint8
+}
+
+func (b LinkBreak) String() string {
+	return fmt.Sprintf("slave %d port %d", b.Slave, b.Port)
+}
+
+type RedundancyStatus struct {
+	// Master was opened with NewRedundantMaster
+	Redundant bool
+	// Port on which a probe frame sent from the primary port returned. This
+	// is the secondary port while the ring is closed, the primary port once
+	// the ring is broken and the slaves loop the frame back, and NoPort if it
+	// was lost.
+	PrimaryReturn MasterPort
+	// Ports found without communication
+	Breaks []LinkBreak
+	// Slaves that did not answer the DL status read
+	Unreachable []uint16
+}
+
+func (s *RedundancyStatus) Broken() bool {
+	return len(s.Breaks) > 0 || len(s.Unreachable) > 0
+}
+
+func (s *RedundancyStatus) String() string {
+	if !s.Redundant {
+		return "not redundant"
+	}
+	if !s.Broken() {
+		return "ring closed"
+	}
+	return fmt.Sprintf("ring broken at %v, unreachable slaves %v", s.Breaks, s.Unreachable)
+}
+
+func (m *Master) IsRedundant() bool {
+	return m.redport != nil && m.context.port.redstate != C.ECT_RED_NONE
+}
+
+// Reads the DL status register of every slave and compares the port links
+// against the ports that were active when ConfigInit ran, to locate breaks
+// in the cable ring. Breaks are reported for non-redundant masters too.
+func (m *Master) RedundancyStatus() *RedundancyStatus {
+	status := &RedundancyStatus{
+		Redundant:     m.IsRedundant(),
+		PrimaryReturn: m.primaryReturn(),
+	}
+
+	for slave := uint16(1); slave <= m.SlaveCount; slave++ {
+		cslave := m.ecSlave(slave)
+
+		dlStatus, err := m.readDLStatus(slave)
+		if err != nil {
+			status.Unreachable = append(status.Unreachable, slave)
+			continue
+		}
+
+		for port := uint8(0); port < 4; port++ {
+			if uint8(cslave.activeports)&(1<<port) == 0 {
+				continue
+			}
+			if !dlStatus.Communication(port) {
+				status.Breaks = append(status.Breaks, LinkBreak{slave, port})
+			}
+		}
+	}
+
+	return status
+}
+
+func (m *Master) primaryReturn() MasterPort {
+	switch C.soem_primary_return(m.context.port, EC_TIMEOUTRET) {
+	case 0:
+		return PrimaryPort
+	case 1:
+		return SecondaryPort
+	default:
+		return NoPort
+	}
+}
+
+// ESC DL status register (0x0110)
+type DLStatus uint16
+
+func (s DLStatus) Link(port uint8) bool {
+	return s&(1<<(4+port)) != 0
+}
+
+func (s DLStatus) LoopClosed(port uint8) bool {
+	return s&(1<<(8+2*port)) != 0
+}
+
+func (s DLStatus) Communication(port uint8) bool {
+	return s&(1<<(9+2*port)) != 0
+}
+
+func (m *Master) readDLStatus(slave uint16) (DLStatus, error) {
+	var buf [2]byte
+	if err := m.readRegister(slave, C.ECT_REG_DLSTAT, buf[:]); err != nil {
+		return 0, err
+	}
+
+	return DLStatus(binary.LittleEndian.Uint16(buf[:])), nil
+}
@@ -0,0 +1,344 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+*/
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+	"unsafe"
+)
+
+type SDOError struct {
+	Slave     uint16
+	Index     uint16
+	SubIndex  uint8
+	AbortCode uint32
+}
+
+func (e *SDOError) Error() string {
+	return fmt.Sprintf("SDO abort on slave %d at 0x%04x:%02x: 0x%08x %s",
+		e.Slave, e.Index, e.SubIndex, e.AbortCode,
+		C.GoString(C.ec_sdoerror2string(C.uint32(e.AbortCode))))
+}
+
+func (m *Master) SDORead(slave, index uint16, subIndex uint8, size int) ([]byte, error) {
+	return m.sdoRead(slave, index, subIndex, false, size)
+}
+
+// Complete access reads all sub-indices of the object, starting at subIndex (0 or 1)
+func (m *Master) SDOReadCA(slave, index uint16, subIndex uint8, size int) ([]byte, error) {
+	return m.sdoRead(slave, index, subIndex, true, size)
+}
+
+func (m *Master) SDOWrite(slave, index uint16, subIndex uint8, data []byte) error {
+	return m.sdoWrite(slave, index, subIndex, false, data)
+}
+
+// Complete access writes all sub-indices of the object, starting at subIndex (0 or 1)
+func (m *Master) SDOWriteCA(slave, index uint16, subIndex uint8, data []byte) error {
+	return m.sdoWrite(slave, index, subIndex, true, data)
+}
+
+// Reads an object entry and decodes it according to dataType. Variable length
+// types (strings, domains) are read with a buffer of EC_MAXMBX bytes.
+func (m *Master) SDOReadValue(slave, index uint16, subIndex uint8, dataType EtherCATDataType) (interface{}, error) {
+	size := dataType.Size()
+	if size == 0 {
+		size = EC_MAXMBX
+	}
+
+	data, err := m.SDORead(slave, index, subIndex, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeValue(dataType, data)
+}
+
+func (m *Master) SDOWriteValue(slave, index uint16, subIndex uint8, dataType EtherCATDataType, value interface{}) error {
+	data, err := EncodeValue(dataType, value)
+	if err != nil {
+		return err
+	}
+
+	return m.SDOWrite(slave, index, subIndex, data)
+}
+
+func (m *Master) sdoRead(slave, index uint16, subIndex uint8, completeAccess bool, size int) ([]byte, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("invalid SDO buffer size %d", size)
+	}
+
+	buf := make([]byte, size)
+	csize := C.int(size)
+
+	wkc := C.ecx_SDOread(m.context,
+		C.ushort(slave),
+		C.ushort(index),
+		C.uchar(subIndex),
+		cBool(completeAccess),
+		&csize,
+		unsafe.Pointer(&buf[0]),
+		C.int(EC_TIMEOUTRXM))
+
+	if wkc <= 0 {
+		return nil, m.sdoError(slave, index, subIndex, int(wkc))
+	}
+
+	return buf[:csize], nil
+}
+
+func (m *Master) sdoWrite(slave, index uint16, subIndex uint8, completeAccess bool, data []byte) error {
+	if len(data) < 1 {
+		return fmt.Errorf("no data to write to 0x%04x:%02x", index, subIndex)
+	}
+
+	wkc := C.ecx_SDOwrite(m.context,
+		C.ushort(slave),
+		C.ushort(index),
+		C.uchar(subIndex),
+		cBool(completeAccess),
+		C.int(len(data)),
+		unsafe.Pointer(&data[0]),
+		C.int(EC_TIMEOUTRXM))
+
+	if wkc <= 0 {
+		return m.sdoError(slave, index, subIndex, int(wkc))
+	}
+
+	return nil
+}
+
+// SOEM signals an abort by returning a zero working counter and pushing the
+// abort code onto the error list.
+func (m *Master) sdoError(slave, index uint16, subIndex uint8, wkc int) error {
+	e := m.findError(func(e *ErrorEvent) bool {
+		return e.Type == EC_ERR_TYPE_SDO_ERROR && e.Slave == slave && e.Index == index
+	})
+	if e != nil {
+		return &SDOError{
+			Slave:     slave,
+			Index:     index,
+			SubIndex:  e.SubIndex,
+			AbortCode: e.AbortCode,
+		}
+	}
+
+	return fmt.Errorf("SDO transfer on slave %d at 0x%04x:%02x failed (wkc %d)", slave, index, subIndex, wkc)
+}
+
+func cBool(b bool) C.boolean {
+	if b {
+		return C.boolean(1)
+	}
+	return C.boolean(0)
+}
+
+// Decodes little-endian object data into the Go type matching dataType
+func DecodeValue(dataType EtherCATDataType, data []byte) (interface{}, error) {
+	size := dataType.Size()
+	if len(data) < size {
+		return nil, fmt.Errorf("%d bytes is too short for %s", len(data), dataType)
+	}
+
+	switch dataType {
+	case ECT_BOOLEAN:
+		return data[0] != 0, nil
+	case ECT_INTEGER8:
+		return int8(data[0]), nil
+	case ECT_INTEGER16:
+		return int16(binary.LittleEndian.Uint16(data)), nil
+	case ECT_INTEGER24:
+		v := int32(uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16)
+		return v << 8 >> 8, nil
+	case ECT_INTEGER32:
+		return int32(binary.LittleEndian.Uint32(data)), nil
+	case ECT_INTEGER64:
+		return int64(binary.LittleEndian.Uint64(data)), nil
+	case ECT_UNSIGNED8, ECT_BIT1, ECT_BIT2, ECT_BIT3, ECT_BIT4,
+		ECT_BIT5, ECT_BIT6, ECT_BIT7, ECT_BIT8:
+		return data[0], nil
+	case ECT_UNSIGNED16:
+		return binary.LittleEndian.Uint16(data), nil
+	case ECT_UNSIGNED24:
+		return uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16, nil
+	case ECT_UNSIGNED32:
+		return binary.LittleEndian.Uint32(data), nil
+	case ECT_UNSIGNED64:
+		return binary.LittleEndian.Uint64(data), nil
+	case ECT_REAL32:
+		return math.Float32frombits(binary.LittleEndian.Uint32(data)), nil
+	case ECT_REAL64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+	case ECT_VISIBLE_STRING:
+		// fixed size strings are padded with NULs
+		return strings.TrimRight(string(data), "\x00"), nil
+	case ECT_UNICODE_STRING:
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(data[i*2:])
+		}
+		for len(units) > 0 && units[len(units)-1] == 0 {
+			units = units[:len(units)-1]
+		}
+		return string(utf16.Decode(units)), nil
+	case ECT_OCTET_STRING, ECT_DOMAIN:
+		return data, nil
+	default:
+		return nil, fmt.Errorf("cannot decode data type %s", dataType)
+	}
+}
+
+// Encodes value as little-endian object data for dataType. Integer types
+// accept any Go integer that fits the target range.
+func EncodeValue(dataType EtherCATDataType, value interface{}) ([]byte, error) {
+	switch dataType {
+	case ECT_VISIBLE_STRING:
+		if s, ok := value.(string); ok {
+			return []byte(s), nil
+		}
+	case ECT_UNICODE_STRING:
+		if s, ok := value.(string); ok {
+			units := utf16.Encode([]rune(s))
+			data := make([]byte, len(units)*2)
+			for i, u := range units {
+				binary.LittleEndian.PutUint16(data[i*2:], u)
+			}
+			return data, nil
+		}
+	case ECT_OCTET_STRING, ECT_DOMAIN:
+		if b, ok := value.([]byte); ok {
+			return b, nil
+		}
+	case ECT_BOOLEAN:
+		if b, ok := value.(bool); ok {
+			if b {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		}
+	case ECT_REAL32:
+		if f, ok := toFloat64(value); ok {
+			data := make([]byte, 4)
+			binary.LittleEndian.PutUint32(data, math.Float32bits(float32(f)))
+			return data, nil
+		}
+	case ECT_REAL64:
+		if f, ok := toFloat64(value); ok {
+			data := make([]byte, 8)
+			binary.LittleEndian.PutUint64(data, math.Float64bits(f))
+			return data, nil
+		}
+	default:
+		size := dataType.Size()
+		if size == 0 {
+			return nil, fmt.Errorf("cannot encode data type %s", dataType)
+		}
+
+		v, ok := toUint64(value)
+		if !ok {
+			break
+		}
+		if !fitsDataType(dataType, value) {
+			return nil, fmt.Errorf("value %v out of range for %s", value, dataType)
+		}
+
+		data := make([]byte, 8)
+		binary.LittleEndian.PutUint64(data, v)
+		return data[:size], nil
+	}
+
+	return nil, fmt.Errorf("cannot encode %T as %s", value, dataType)
+}
+
+func fitsDataType(dataType EtherCATDataType, value interface{}) bool {
+	bits := uint(dataType.Size() * 8)
+	switch dataType {
+	case ECT_BIT1, ECT_BIT2, ECT_BIT3, ECT_BIT4, ECT_BIT5, ECT_BIT6, ECT_BIT7, ECT_BIT8:
+		bits = uint(dataType-ECT_BIT1) + 1
+	}
+
+	switch dataType {
+	case ECT_INTEGER8, ECT_INTEGER16, ECT_INTEGER24, ECT_INTEGER32, ECT_INTEGER64:
+		v, ok := toInt64(value)
+		if !ok {
+			return false
+		}
+		if bits == 64 {
+			return true
+		}
+		return v >= -(1<<(bits-1)) && v < 1<<(bits-1)
+	default:
+		if v, ok := toInt64(value); ok && v < 0 {
+			return false
+		}
+		v, _ := toUint64(value)
+		if bits == 64 {
+			return true
+		}
+		return v < 1<<bits
+	}
+}
+
+func toUint64(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case int:
+		return uint64(v), true
+	case int8:
+		return uint64(v), true
+	case int16:
+		return uint64(v), true
+	case int32:
+		return uint64(v), true
+	case int64:
+		return uint64(v), true
+	case uint:
+		return uint64(v), true
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	}
+	return 0, false
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	}
+	v, ok := toUint64(value)
+	return int64(v), ok
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
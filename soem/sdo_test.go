@@ -0,0 +1,119 @@
+package soem
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	tests := []struct {
+		dataType EtherCATDataType
+		value    interface{}
+		data     []byte
+	}{
+		{ECT_BOOLEAN, true, []byte{0x01}},
+		{ECT_BOOLEAN, false, []byte{0x00}},
+		{ECT_INTEGER8, int8(-2), []byte{0xfe}},
+		{ECT_INTEGER16, int16(-300), []byte{0xd4, 0xfe}},
+		{ECT_INTEGER24, int32(-2), []byte{0xfe, 0xff, 0xff}},
+		{ECT_INTEGER24, int32(0x123456), []byte{0x56, 0x34, 0x12}},
+		{ECT_INTEGER32, int32(-70000), []byte{0x90, 0xee, 0xfe, 0xff}},
+		{ECT_INTEGER64, int64(math.MinInt64), []byte{0, 0, 0, 0, 0, 0, 0, 0x80}},
+		{ECT_UNSIGNED8, uint8(0xab), []byte{0xab}},
+		{ECT_UNSIGNED16, uint16(0x1a00), []byte{0x00, 0x1a}},
+		{ECT_UNSIGNED24, uint32(0xabcdef), []byte{0xef, 0xcd, 0xab}},
+		{ECT_UNSIGNED32, uint32(0x60000110), []byte{0x10, 0x01, 0x00, 0x60}},
+		{ECT_UNSIGNED64, uint64(math.MaxUint64), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{ECT_REAL32, float32(1.5), []byte{0x00, 0x00, 0xc0, 0x3f}},
+		{ECT_REAL64, float64(-2), []byte{0, 0, 0, 0, 0, 0, 0, 0xc0}},
+		{ECT_BIT1, uint8(1), []byte{0x01}},
+		{ECT_BIT4, uint8(0x0f), []byte{0x0f}},
+		{ECT_BIT8, uint8(0xff), []byte{0xff}},
+		{ECT_VISIBLE_STRING, "EL1008", []byte("EL1008")},
+		{ECT_UNICODE_STRING, "Grüße", []byte{'G', 0, 'r', 0, 0xfc, 0, 0xdf, 0, 'e', 0}},
+		{ECT_UNICODE_STRING, "\U0001F600", []byte{0x3d, 0xd8, 0x00, 0xde}},
+		{ECT_OCTET_STRING, []byte{1, 2, 3}, []byte{1, 2, 3}},
+		{ECT_DOMAIN, []byte{0xde, 0xad}, []byte{0xde, 0xad}},
+	}
+
+	for _, tt := range tests {
+		data, err := EncodeValue(tt.dataType, tt.value)
+		if err != nil {
+			t.Errorf("EncodeValue(%s, %v): %v", tt.dataType, tt.value, err)
+			continue
+		}
+		if !bytes.Equal(data, tt.data) {
+			t.Errorf("EncodeValue(%s, %v) = % x, want % x", tt.dataType, tt.value, data, tt.data)
+		}
+
+		value, err := DecodeValue(tt.dataType, data)
+		if err != nil {
+			t.Errorf("DecodeValue(%s, % x): %v", tt.dataType, data, err)
+			continue
+		}
+		if !reflect.DeepEqual(value, tt.value) {
+			t.Errorf("DecodeValue(%s, % x) = %#v, want %#v", tt.dataType, data, value, tt.value)
+		}
+	}
+}
+
+func TestDecodeStringPadding(t *testing.T) {
+	tests := []struct {
+		dataType EtherCATDataType
+		data     []byte
+		want     string
+	}{
+		{ECT_VISIBLE_STRING, []byte("EK1100\x00\x00\x00\x00"), "EK1100"},
+		{ECT_VISIBLE_STRING, []byte("\x00\x00"), ""},
+		{ECT_UNICODE_STRING, []byte{'A', 0, 'B', 0, 0, 0, 0, 0}, "AB"},
+		// an odd trailing byte is not a whole code unit
+		{ECT_UNICODE_STRING, []byte{'A', 0, 'B'}, "A"},
+	}
+
+	for _, tt := range tests {
+		value, err := DecodeValue(tt.dataType, tt.data)
+		if err != nil {
+			t.Errorf("DecodeValue(%s, %q): %v", tt.dataType, tt.data, err)
+			continue
+		}
+		if value != tt.want {
+			t.Errorf("DecodeValue(%s, %q) = %q, want %q", tt.dataType, tt.data, value, tt.want)
+		}
+	}
+}
+
+func TestEncodeValueRange(t *testing.T) {
+	tests := []struct {
+		dataType EtherCATDataType
+		value    interface{}
+		ok       bool
+	}{
+		{ECT_UNSIGNED8, 255, true},
+		{ECT_UNSIGNED8, 256, false},
+		{ECT_UNSIGNED8, -1, false},
+		{ECT_INTEGER8, -128, true},
+		{ECT_INTEGER8, 128, false},
+		{ECT_INTEGER16, int64(-32769), false},
+		{ECT_UNSIGNED24, 1 << 24, false},
+		{ECT_BIT3, 7, true},
+		{ECT_BIT3, 8, false},
+		{ECT_BOOLEAN, 1, false},
+		{ECT_VISIBLE_STRING, []byte("x"), false},
+		{ECT_TIME_OF_DAY, 0, false},
+	}
+
+	for _, tt := range tests {
+		_, err := EncodeValue(tt.dataType, tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("EncodeValue(%s, %v) error = %v, want ok %t", tt.dataType, tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestDecodeValueShort(t *testing.T) {
+	if _, err := DecodeValue(ECT_UNSIGNED32, []byte{1, 2, 3}); err == nil {
+		t.Error("DecodeValue accepted 3 bytes for UNSIGNED32")
+	}
+}
@@ -0,0 +1,353 @@
+package soem
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Decoded contents of a slave's SII EEPROM
+type SII struct {
+	PDIControl       uint16
+	PDIConfig        uint16
+	SyncImpulseLen   uint16
+	PDIConfig2       uint16
+	Alias            uint16
+	Checksum         uint8
+	VendorID         uint32
+	ProductCode      uint32
+	Revision         uint32
+	SerialNumber     uint32
+	BootRxMailbox    SIIMailbox
+	BootTxMailbox    SIIMailbox
+	StdRxMailbox     SIIMailbox
+	StdTxMailbox     SIIMailbox
+	MailboxProtocols MailboxProtocol
+	// EEPROM size in bytes
+	Size    int
+	Version uint16
+
+	// String category, index 1 first
+	Strings []string
+	General *SIIGeneral
+	FMMUs   []SIIFMMU
+	SMs     []SIISyncManager
+	TxPDOs  []SIIPDO
+	RxPDOs  []SIIPDO
+	DCs     []SIIDC
+	// Categories not decoded, by type
+	Other map[SIICategory][]byte
+}
+
+type SIIMailbox struct {
+	Offset uint16
+	Size   uint16
+}
+
+type SIIGeneral struct {
+	GroupIdx       uint8
+	ImageIdx       uint8
+	OrderIdx       uint8
+	NameIdx        uint8
+	CoEDetails     uint8
+	FoEDetails     uint8
+	EoEDetails     uint8
+	SoEChannels    uint8
+	DS402Channels  uint8
+	SysmanClass    uint8
+	Flags          uint8
+	CurrentOnEBus  int16
+	PhysicalPort   uint16
+	PhysicalMemory uint16
+}
+
+type SIIFMMU uint8
+
+const (
+	SII_FMMU_UNUSED  SIIFMMU = 0
+	SII_FMMU_OUTPUTS SIIFMMU = 1
+	SII_FMMU_INPUTS  SIIFMMU = 2
+	SII_FMMU_SMSTATE SIIFMMU = 3
+)
+
+func (f SIIFMMU) String() string {
+	switch f {
+	case SII_FMMU_UNUSED:
+		return "unused"
+	case SII_FMMU_OUTPUTS:
+		return "outputs"
+	case SII_FMMU_INPUTS:
+		return "inputs"
+	case SII_FMMU_SMSTATE:
+		return "SM status"
+	default:
+		return fmt.Sprintf("0x%02x", uint8(f))
+	}
+}
+
+type SIISyncManager struct {
+	StartAddress uint16
+	Length       uint16
+	Control      uint8
+	Status       uint8
+	Enable       uint8
+	// 1 mailbox out, 2 mailbox in, 3 outputs, 4 inputs
+	Type uint8
+}
+
+type SIIPDO struct {
+	Index   uint16
+	SM      uint8
+	Synch   uint8
+	NameIdx uint8
+	Flags   uint16
+	Entries []SIIPDOEntry
+	Name    string
+}
+
+type SIIPDOEntry struct {
+	Index    uint16
+	SubIndex uint8
+	NameIdx  uint8
+	DataType EtherCATDataType
+	BitLen   uint8
+	Flags    uint16
+	Name     string
+}
+
+// Distributed clock operation mode
+type SIIDC struct {
+	CycleTime0     uint32
+	ShiftTime0     uint32
+	ShiftTime1     uint32
+	Sync1Factor    int16
+	AssignActivate uint16
+	Sync0Factor    int16
+	NameIdx        uint8
+	DescIdx        uint8
+	Name           string
+}
+
+// Returns string index from the string category, "" for index 0
+func (s *SII) StringAt(index uint8) string {
+	if index == 0 || int(index) > len(s.Strings) {
+		return ""
+	}
+	return s.Strings[index-1]
+}
+
+// Decodes an SII EEPROM image as read by Master.ReadEEPROM
+func DecodeSII(data []byte) (*SII, error) {
+	if len(data) < EC_SII_CATEGORIES*2 {
+		return nil, fmt.Errorf("SII of %d bytes is too short", len(data))
+	}
+
+	word := func(address int) uint16 {
+		return binary.LittleEndian.Uint16(data[address*2:])
+	}
+	dword := func(address int) uint32 {
+		return binary.LittleEndian.Uint32(data[address*2:])
+	}
+	mailbox := func(address int) SIIMailbox {
+		return SIIMailbox{word(address), word(address + 1)}
+	}
+
+	s := &SII{
+		PDIControl:       word(0),
+		PDIConfig:        word(1),
+		SyncImpulseLen:   word(2),
+		PDIConfig2:       word(3),
+		Alias:            word(EC_SII_ALIAS),
+		Checksum:         uint8(word(EC_SII_CHECKSUM)),
+		VendorID:         dword(EC_SII_VENDOR),
+		ProductCode:      dword(EC_SII_PRODUCT),
+		Revision:         dword(EC_SII_REVISION),
+		SerialNumber:     dword(EC_SII_SERIAL),
+		BootRxMailbox:    mailbox(0x14),
+		BootTxMailbox:    mailbox(0x16),
+		StdRxMailbox:     mailbox(0x18),
+		StdTxMailbox:     mailbox(0x1A),
+		MailboxProtocols: MailboxProtocol(word(EC_SII_MBX_PROTOCOL)),
+		Size:             (int(word(EC_SII_SIZE)) + 1) * 128,
+		Version:          word(EC_SII_VERSION),
+		Other:            make(map[SIICategory][]byte),
+	}
+
+	address := EC_SII_CATEGORIES
+	for (address+2)*2 <= len(data) {
+		category := SIICategory(word(address))
+		if category == ECT_SII_END {
+			break
+		}
+		length := int(word(address+1)) * 2
+		start := (address + 2) * 2
+		if start+length > len(data) {
+			return nil, fmt.Errorf("SII category %d at word 0x%04x exceeds the EEPROM", category, address)
+		}
+		body := data[start : start+length]
+
+		var err error
+		switch category {
+		case ECT_SII_STRING:
+			s.Strings, err = decodeSIIStrings(body)
+		case ECT_SII_GENERAL:
+			s.General, err = decodeSIIGeneral(body)
+		case ECT_SII_FMMU:
+			for _, b := range body {
+				s.FMMUs = append(s.FMMUs, SIIFMMU(b))
+			}
+		case ECT_SII_SM:
+			s.SMs = decodeSIISyncManagers(body)
+		case ECT_SII_TXPDO:
+			s.TxPDOs, err = decodeSIIPDOs(body)
+		case ECT_SII_RXPDO:
+			s.RxPDOs, err = decodeSIIPDOs(body)
+		case ECT_SII_DC:
+			s.DCs = decodeSIIDCs(body)
+		default:
+			s.Other[category] = body
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		address += 2 + length/2
+	}
+
+	for i := range s.TxPDOs {
+		s.resolveNames(&s.TxPDOs[i])
+	}
+	for i := range s.RxPDOs {
+		s.resolveNames(&s.RxPDOs[i])
+	}
+	for i := range s.DCs {
+		s.DCs[i].Name = s.StringAt(s.DCs[i].NameIdx)
+	}
+
+	return s, nil
+}
+
+// Name of the device from the general category
+func (s *SII) Name() string {
+	if s.General == nil {
+		return ""
+	}
+	return s.StringAt(s.General.NameIdx)
+}
+
+func (s *SII) resolveNames(pdo *SIIPDO) {
+	pdo.Name = s.StringAt(pdo.NameIdx)
+	for i := range pdo.Entries {
+		pdo.Entries[i].Name = s.StringAt(pdo.Entries[i].NameIdx)
+	}
+}
+
+func decodeSIIStrings(body []byte) ([]string, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	count := int(body[0])
+	strs := make([]string, 0, count)
+	offset := 1
+	for i := 0; i < count; i++ {
+		if offset >= len(body) {
+			return nil, fmt.Errorf("SII string %d exceeds the string category", i+1)
+		}
+		l := int(body[offset])
+		offset++
+		if offset+l > len(body) {
+			return nil, fmt.Errorf("SII string %d exceeds the string category", i+1)
+		}
+		strs = append(strs, string(body[offset:offset+l]))
+		offset += l
+	}
+	return strs, nil
+}
+
+func decodeSIIGeneral(body []byte) (*SIIGeneral, error) {
+	if len(body) < 20 {
+		return nil, fmt.Errorf("SII general category of %d bytes is too short", len(body))
+	}
+
+	return &SIIGeneral{
+		GroupIdx:       body[0],
+		ImageIdx:       body[1],
+		OrderIdx:       body[2],
+		NameIdx:        body[3],
+		CoEDetails:     body[5],
+		FoEDetails:     body[6],
+		EoEDetails:     body[7],
+		SoEChannels:    body[8],
+		DS402Channels:  body[9],
+		SysmanClass:    body[10],
+		Flags:          body[11],
+		CurrentOnEBus:  int16(binary.LittleEndian.Uint16(body[12:])),
+		PhysicalPort:   binary.LittleEndian.Uint16(body[16:]),
+		PhysicalMemory: binary.LittleEndian.Uint16(body[18:]),
+	}, nil
+}
+
+func decodeSIISyncManagers(body []byte) []SIISyncManager {
+	var sms []SIISyncManager
+	for offset := 0; offset+8 <= len(body); offset += 8 {
+		sms = append(sms, SIISyncManager{
+			StartAddress: binary.LittleEndian.Uint16(body[offset:]),
+			Length:       binary.LittleEndian.Uint16(body[offset+2:]),
+			Control:      body[offset+4],
+			Status:       body[offset+5],
+			Enable:       body[offset+6],
+			Type:         body[offset+7],
+		})
+	}
+	return sms
+}
+
+func decodeSIIPDOs(body []byte) ([]SIIPDO, error) {
+	var pdos []SIIPDO
+	offset := 0
+	for offset+8 <= len(body) {
+		pdo := SIIPDO{
+			Index:   binary.LittleEndian.Uint16(body[offset:]),
+			SM:      body[offset+3],
+			Synch:   body[offset+4],
+			NameIdx: body[offset+5],
+			Flags:   binary.LittleEndian.Uint16(body[offset+6:]),
+		}
+		entries := int(body[offset+2])
+		offset += 8
+
+		if offset+entries*8 > len(body) {
+			return nil, fmt.Errorf("SII PDO 0x%04x exceeds its category", pdo.Index)
+		}
+		for i := 0; i < entries; i++ {
+			pdo.Entries = append(pdo.Entries, SIIPDOEntry{
+				Index:    binary.LittleEndian.Uint16(body[offset:]),
+				SubIndex: body[offset+2],
+				NameIdx:  body[offset+3],
+				DataType: EtherCATDataType(body[offset+4]),
+				BitLen:   body[offset+5],
+				Flags:    binary.LittleEndian.Uint16(body[offset+6:]),
+			})
+			offset += 8
+		}
+		pdos = append(pdos, pdo)
+	}
+	return pdos, nil
+}
+
+func decodeSIIDCs(body []byte) []SIIDC {
+	var dcs []SIIDC
+	for offset := 0; offset+24 <= len(body); offset += 24 {
+		dcs = append(dcs, SIIDC{
+			CycleTime0:     binary.LittleEndian.Uint32(body[offset:]),
+			ShiftTime0:     binary.LittleEndian.Uint32(body[offset+4:]),
+			ShiftTime1:     binary.LittleEndian.Uint32(body[offset+8:]),
+			Sync1Factor:    int16(binary.LittleEndian.Uint16(body[offset+12:])),
+			AssignActivate: binary.LittleEndian.Uint16(body[offset+14:]),
+			Sync0Factor:    int16(binary.LittleEndian.Uint16(body[offset+16:])),
+			NameIdx:        body[offset+18],
+			DescIdx:        body[offset+19],
+		})
+	}
+	return dcs
+}
@@ -0,0 +1,228 @@
+package soem
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// SII configuration area of an ESC with a 16 bit SPI PDI, as found on
+// LAN9252 based slaves: PDI control 0x0C80, PDI configuration 0x6E00 and
+// sync impulse length 0x0044
+var siiConfigArea = []byte{0x80, 0x0c, 0x00, 0x6e, 0x44, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}
+
+type siiCategory struct {
+	category SIICategory
+	body     []byte
+}
+
+// Builds an SII image with the identity of an EL3102 and the given categories
+func buildSII(categories ...siiCategory) []byte {
+	data := make([]byte, EC_SII_CATEGORIES*2)
+	copy(data, siiConfigArea)
+	le := binary.LittleEndian
+	le.PutUint16(data[EC_SII_ALIAS*2:], 1001)
+	data[EC_SII_CHECKSUM*2] = SIIChecksum(data)
+	le.PutUint32(data[EC_SII_VENDOR*2:], 0x00000002)
+	le.PutUint32(data[EC_SII_PRODUCT*2:], 0x0c1e3052)
+	le.PutUint32(data[EC_SII_REVISION*2:], 0x00120000)
+	le.PutUint32(data[EC_SII_SERIAL*2:], 0x0000162e)
+	// bootstrap mailboxes
+	le.PutUint16(data[0x14*2:], 0x1000)
+	le.PutUint16(data[0x15*2:], 0x00f4)
+	le.PutUint16(data[0x16*2:], 0x10f4)
+	le.PutUint16(data[0x17*2:], 0x00f4)
+	// standard mailboxes
+	le.PutUint16(data[0x18*2:], 0x1000)
+	le.PutUint16(data[0x19*2:], 0x0080)
+	le.PutUint16(data[0x1A*2:], 0x1080)
+	le.PutUint16(data[0x1B*2:], 0x0080)
+	le.PutUint16(data[EC_SII_MBX_PROTOCOL*2:], uint16(ECT_MBXPROT_COE))
+	// 16 kbit
+	le.PutUint16(data[EC_SII_SIZE*2:], 0x000f)
+	le.PutUint16(data[EC_SII_VERSION*2:], 0x0001)
+
+	for _, c := range categories {
+		body := append([]byte(nil), c.body...)
+		if len(body)%2 != 0 {
+			body = append(body, 0)
+		}
+		header := make([]byte, 4)
+		le.PutUint16(header, uint16(c.category))
+		le.PutUint16(header[2:], uint16(len(body)/2))
+		data = append(data, header...)
+		data = append(data, body...)
+	}
+	return append(data, 0xff, 0xff)
+}
+
+func siiStrings(strs ...string) []byte {
+	body := []byte{byte(len(strs))}
+	for _, s := range strs {
+		body = append(body, byte(len(s)))
+		body = append(body, s...)
+	}
+	return body
+}
+
+var (
+	el3102Strings = siiStrings("EL3102", "AI Inputs", "EL3102 2Ch. Ana. Input +/-10V", "AI TxPDO-Map Ch.1", "Status", "Value")
+	el3102General = []byte{
+		2, 0, 1, 3, // group, image, order, name
+		0,                      // reserved
+		0x23, 0, 0, 0, 0, 0, 0, // CoE details, FoE, EoE, SoE, DS402, sysman class, flags
+		0x82, 0x00, // 130 mA from E-bus
+		0x00, 0x00, // group index high
+		0x11, 0x00, // ports 0 and 1 MII
+		0x00, 0x00, // physical memory
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	el3102FMMU = []byte{byte(SII_FMMU_INPUTS), byte(SII_FMMU_SMSTATE)}
+	el3102SM   = []byte{
+		0x00, 0x10, 0x80, 0x00, 0x26, 0x00, 0x01, 0x01,
+		0x80, 0x10, 0x80, 0x00, 0x22, 0x00, 0x01, 0x02,
+		0x00, 0x11, 0x00, 0x00, 0x04, 0x00, 0x00, 0x03,
+		0x80, 0x11, 0x04, 0x00, 0x20, 0x00, 0x01, 0x04,
+	}
+	el3102TxPDO = []byte{
+		0x00, 0x1a, 2, 3, 0, 4, 0x00, 0x00,
+		0x00, 0x60, 0x01, 5, byte(ECT_UNSIGNED16), 16, 0x00, 0x00,
+		0x00, 0x60, 0x11, 6, byte(ECT_INTEGER16), 16, 0x00, 0x00,
+	}
+	el3102DC = []byte{
+		0x40, 0x42, 0x0f, 0x00, // 1 ms
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, // sync1 factor
+		0x00, 0x03, // assign activate
+		0x01, 0x00, // sync0 factor
+		2, 0, // name, description
+		0, 0, 0, 0,
+	}
+)
+
+func TestDecodeSII(t *testing.T) {
+	data := buildSII(
+		siiCategory{ECT_SII_STRING, el3102Strings},
+		siiCategory{ECT_SII_GENERAL, el3102General},
+		siiCategory{ECT_SII_FMMU, el3102FMMU},
+		siiCategory{ECT_SII_SM, el3102SM},
+		siiCategory{ECT_SII_TXPDO, el3102TxPDO},
+		siiCategory{ECT_SII_DC, el3102DC},
+		siiCategory{1, []byte{0xaa, 0x55}},
+	)
+	// trailing erased EEPROM after the end category
+	for len(data) < 2048 {
+		data = append(data, 0xff)
+	}
+
+	s, err := DecodeSII(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"PDIControl", s.PDIControl, uint16(0x0c80)},
+		{"PDIConfig", s.PDIConfig, uint16(0x6e00)},
+		{"SyncImpulseLen", s.SyncImpulseLen, uint16(0x0044)},
+		{"Alias", s.Alias, uint16(1001)},
+		{"Checksum", s.Checksum, uint8(0x0d)},
+		{"VendorID", s.VendorID, uint32(2)},
+		{"ProductCode", s.ProductCode, uint32(0x0c1e3052)},
+		{"Revision", s.Revision, uint32(0x00120000)},
+		{"SerialNumber", s.SerialNumber, uint32(0x162e)},
+		{"BootRxMailbox", s.BootRxMailbox, SIIMailbox{0x1000, 0xf4}},
+		{"BootTxMailbox", s.BootTxMailbox, SIIMailbox{0x10f4, 0xf4}},
+		{"StdRxMailbox", s.StdRxMailbox, SIIMailbox{0x1000, 0x80}},
+		{"StdTxMailbox", s.StdTxMailbox, SIIMailbox{0x1080, 0x80}},
+		{"MailboxProtocols", s.MailboxProtocols, ECT_MBXPROT_COE},
+		{"Size", s.Size, 2048},
+		{"Version", s.Version, uint16(1)},
+		{"Strings", s.Strings, []string{"EL3102", "AI Inputs", "EL3102 2Ch. Ana. Input +/-10V", "AI TxPDO-Map Ch.1", "Status", "Value"}},
+		{"Name", s.Name(), "EL3102 2Ch. Ana. Input +/-10V"},
+		{"General", s.General, &SIIGeneral{
+			GroupIdx:      2,
+			OrderIdx:      1,
+			NameIdx:       3,
+			CoEDetails:    0x23,
+			CurrentOnEBus: 130,
+			PhysicalPort:  0x0011,
+		}},
+		{"FMMUs", s.FMMUs, []SIIFMMU{SII_FMMU_INPUTS, SII_FMMU_SMSTATE}},
+		{"SMs", s.SMs, []SIISyncManager{
+			{0x1000, 0x80, 0x26, 0, 1, 1},
+			{0x1080, 0x80, 0x22, 0, 1, 2},
+			{0x1100, 0, 0x04, 0, 0, 3},
+			{0x1180, 4, 0x20, 0, 1, 4},
+		}},
+		{"TxPDOs", s.TxPDOs, []SIIPDO{{
+			Index:   0x1a00,
+			SM:      3,
+			NameIdx: 4,
+			Name:    "AI TxPDO-Map Ch.1",
+			Entries: []SIIPDOEntry{
+				{Index: 0x6000, SubIndex: 0x01, NameIdx: 5, DataType: ECT_UNSIGNED16, BitLen: 16, Name: "Status"},
+				{Index: 0x6000, SubIndex: 0x11, NameIdx: 6, DataType: ECT_INTEGER16, BitLen: 16, Name: "Value"},
+			},
+		}}},
+		{"RxPDOs", s.RxPDOs, []SIIPDO(nil)},
+		{"DCs", s.DCs, []SIIDC{{
+			CycleTime0:     1000000,
+			AssignActivate: 0x0300,
+			Sync0Factor:    1,
+			NameIdx:        2,
+			Name:           "AI Inputs",
+		}}},
+		{"Other", s.Other, map[SIICategory][]byte{1: {0xaa, 0x55}}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %+v, want %+v", c.name, c.got, c.want)
+		}
+	}
+
+	if got := s.StringAt(0); got != "" {
+		t.Errorf("StringAt(0) = %q, want \"\"", got)
+	}
+	if got := s.StringAt(7); got != "" {
+		t.Errorf("StringAt(7) = %q, want \"\"", got)
+	}
+}
+
+func TestDecodeSIIErrors(t *testing.T) {
+	truncated := buildSII(siiCategory{ECT_SII_TXPDO, el3102TxPDO})
+	// cut the image inside the PDO category and drop the end marker
+	truncated = truncated[:len(truncated)-2-8]
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no categories", make([]byte, EC_SII_CATEGORIES*2-1)},
+		{"category exceeds EEPROM", truncated},
+		{"string exceeds category", buildSII(siiCategory{ECT_SII_STRING, []byte{2, 3, 'a', 'b', 'c', 9, 'd'}})},
+		{"string count exceeds category", buildSII(siiCategory{ECT_SII_STRING, []byte{3, 1, 'a'}})},
+		{"short general", buildSII(siiCategory{ECT_SII_GENERAL, el3102General[:18]})},
+		{"PDO entries exceed category", buildSII(siiCategory{ECT_SII_RXPDO, el3102TxPDO[:16]})},
+	}
+
+	for _, tt := range tests {
+		if s, err := DecodeSII(tt.data); err == nil {
+			t.Errorf("%s: decoded %+v", tt.name, s)
+		}
+	}
+}
+
+func TestDecodeSIIWithoutEnd(t *testing.T) {
+	data := buildSII(siiCategory{ECT_SII_FMMU, el3102FMMU})
+	s, err := DecodeSII(data[:len(data)-2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.FMMUs, []SIIFMMU{SII_FMMU_INPUTS, SII_FMMU_SMSTATE}) {
+		t.Errorf("FMMUs = %v", s.FMMUs)
+	}
+}
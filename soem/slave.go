@@ -12,10 +12,14 @@ package soem
 import "C"
 import (
 	"fmt"
+	"time"
 	"unsafe"
 )
 
 type Slave struct {
+	// Position in the network, as used to address the slave through Master
+	Position uint16
+
 	// Manufacturer from EEprom
 	VendorID uint32
 	// ID from EEprom
@@ -40,9 +44,28 @@ type Slave struct {
 	// Device type
 	DeviceType uint16
 
+	// Supported mailbox protocols
+	MailboxProtocols MailboxProtocol
+
+	// Process data group
+	Group uint8
+
 	PDO *SlavePDO
 
+	// Parent slave position, 0 if connected to the master
+	Parent uint16
+	// Port on the parent this slave is connected to
+	ParentPort uint8
+	// Port on this slave frames enter through
+	EntryPort uint8
+	// Number of ports with a link
+	Topology uint8
+	// Bitmask of ports with a link, bit 0 for port 0
+	ActivePorts uint8
+
 	HasDC bool
+	// Propagation delay from the reference clock, set by ConfigDC
+	PropagationDelay time.Duration
 
 	D uint8
 }
@@ -56,9 +79,23 @@ type SlavePDO struct {
 	OutputBits uint16
 	// output bytes, if Obits < 8 then Obytes = 0
 	OutputBytes uint32
+	// startbit in first input byte
+	InputStartBit uint8
+	// startbit in first output byte
+	OutputStartBit uint8
+	// Bit offset of the inputs from the start of the group's input image
+	InputOffset uint
+	// Bit offset of the outputs from the start of the group's output image
+	OutputOffset uint
 
 	inputBuffer  *(C.uchar)
 	outputBuffer *(C.uchar)
+
+	inputs  *ProcessImage
+	outputs *ProcessImage
+
+	// Named variables, populated by Master.ReadPDOMapping
+	Variables []*Variable
 }
 
 func (s *Slave) Read() []byte {
@@ -72,16 +109,40 @@ func (s *Slave) Read() []byte {
 	return nil
 }
 
-func (s *Slave) Write(data []byte) {
+func (s *Slave) Write(data []byte) error {
 	if s.PDO != nil {
 		l := s.PDO.OutputBytes
 		if s.PDO.OutputBytes < 1 && s.PDO.OutputBits > 0 {
 			l = 1
 		}
-		l = l
+		if uint32(len(data)) > l {
+			return fmt.Errorf("%d bytes exceeds %d output bytes of slave %s", len(data), l, s.Name)
+		}
+		if len(data) == 0 {
+			return nil
+		}
 
 		C.memcpy(unsafe.Pointer(s.PDO.outputBuffer), unsafe.Pointer(&data[0]), C.size_t(len(data)))
 	}
+	return nil
+}
+
+// Typed access to the slave's inputs. Nil until the slave has been mapped,
+// in which case the accessors return an error.
+func (s *Slave) Inputs() *ProcessImage {
+	if s.PDO == nil {
+		return nil
+	}
+	return s.PDO.inputs
+}
+
+// Typed access to the slave's outputs. Nil until the slave has been mapped,
+// in which case the accessors return an error.
+func (s *Slave) Outputs() *ProcessImage {
+	if s.PDO == nil {
+		return nil
+	}
+	return s.PDO.outputs
 }
 
 func (slave *Slave) String() string {
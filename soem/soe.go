@@ -0,0 +1,328 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+*/
+import "C"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// Elements of an IDN, combined to select which are transferred
+type SoEElement uint8
+
+const (
+	EC_SOE_DATASTATE_B SoEElement = 0x01
+	EC_SOE_NAME_B      SoEElement = 0x02
+	EC_SOE_ATTRIBUTE_B SoEElement = 0x04
+	EC_SOE_UNIT_B      SoEElement = 0x08
+	EC_SOE_MIN_B       SoEElement = 0x10
+	EC_SOE_MAX_B       SoEElement = 0x20
+	EC_SOE_VALUE_B     SoEElement = 0x40
+	EC_SOE_DEFAULT_B   SoEElement = 0x80
+)
+
+// Drives per SoE slave
+const EC_SOE_MAXDRIVES = 8
+
+// SoE error code of an IDN the drive does not have
+const EC_SOE_ERR_NOIDN = 0x1001
+
+// IDNs of the cyclic data configuration lists
+const (
+	EC_IDN_ATCONFIG  = 16
+	EC_IDN_MDTCONFIG = 24
+)
+
+// Error reported by an SoE drive
+type SoEError struct {
+	Slave     uint16
+	IDN       uint16
+	ErrorCode uint16
+}
+
+func (e *SoEError) Error() string {
+	return fmt.Sprintf("SoE error on slave %d at %s: 0x%04x", e.Slave, FormatIDN(e.IDN), e.ErrorCode)
+}
+
+// Formats an IDN as S-0-0024 or P-0-0024, with the parameter set
+func FormatIDN(idn uint16) string {
+	kind := "S"
+	if idn&0x8000 != 0 {
+		kind = "P"
+	}
+	return fmt.Sprintf("%s-%d-%04d", kind, (idn>>12)&0x7, idn&0xFFF)
+}
+
+// Reads the elements of idn on drive of slave into a buffer of size bytes
+func (m *Master) SoERead(slave uint16, drive uint8, elements SoEElement, idn uint16, size int) ([]byte, error) {
+	if slave < 1 || slave > m.SlaveCount {
+		return nil, fmt.Errorf("no slave %d", slave)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid read size %d for %s", size, FormatIDN(idn))
+	}
+
+	buf := C.malloc(C.size_t(size))
+	defer C.free(buf)
+
+	psize := C.int(size)
+	wkc := C.ecx_SoEread(m.context, C.uint16(slave), C.uint8(drive), C.uint8(elements),
+		C.uint16(idn), &psize, buf, EC_TIMEOUTRXM)
+	if wkc <= 0 {
+		return nil, m.soeError(slave, idn, int(wkc))
+	}
+	return C.GoBytes(buf, psize), nil
+}
+
+// Writes the elements of idn on drive of slave
+func (m *Master) SoEWrite(slave uint16, drive uint8, elements SoEElement, idn uint16, data []byte) error {
+	if slave < 1 || slave > m.SlaveCount {
+		return fmt.Errorf("no slave %d", slave)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("no data to write to %s", FormatIDN(idn))
+	}
+
+	wkc := C.ecx_SoEwrite(m.context, C.uint16(slave), C.uint8(drive), C.uint8(elements),
+		C.uint16(idn), C.int(len(data)), unsafe.Pointer(&data[0]), EC_TIMEOUTRXM)
+	if wkc <= 0 {
+		return m.soeError(slave, idn, int(wkc))
+	}
+	return nil
+}
+
+func (m *Master) soeError(slave, idn uint16, wkc int) error {
+	e := m.findError(func(e *ErrorEvent) bool {
+		return e.Type == EC_ERR_TYPE_SOE_ERROR && e.Slave == slave && e.Index == idn
+	})
+	if e != nil {
+		return &SoEError{Slave: slave, IDN: idn, ErrorCode: e.ErrorCode}
+	}
+
+	return fmt.Errorf("SoE transfer on slave %d at %s failed (wkc %d)", slave, FormatIDN(idn), wkc)
+}
+
+// Decoded attribute element of an IDN
+type SoEAttribute struct {
+	// Conversion factor for display
+	Factor uint16
+	// Data length in bytes, or element length of a list
+	Length int
+	// Variable length list, prefixed with current and maximum length
+	List bool
+	// Procedure command
+	Command bool
+	// 0 binary, 1 unsigned, 2 signed, 3 hex, 4 text, 5 IDN, 6 float
+	DisplayType uint8
+	Decimals    uint8
+	// Write protected in communication phases 2, 3 and 4
+	WriteProtectedPreOp  bool
+	WriteProtectedSafeOp bool
+	WriteProtectedOp     bool
+}
+
+func decodeSoEAttribute(v uint32) SoEAttribute {
+	return SoEAttribute{
+		Factor:               uint16(v),
+		Length:               1 << ((v >> 16) & 0x3),
+		List:                 v&(1<<18) != 0,
+		Command:              v&(1<<19) != 0,
+		DisplayType:          uint8(v>>20) & 0x7,
+		Decimals:             uint8(v>>24) & 0xF,
+		WriteProtectedPreOp:  v&(1<<28) != 0,
+		WriteProtectedSafeOp: v&(1<<29) != 0,
+		WriteProtectedOp:     v&(1<<30) != 0,
+	}
+}
+
+// Data type matching the attribute, for scalar IDNs
+func (a SoEAttribute) DataType() EtherCATDataType {
+	if a.List {
+		return ECT_OCTET_STRING
+	}
+
+	switch a.DisplayType {
+	case 2:
+		switch a.Length {
+		case 1:
+			return ECT_INTEGER8
+		case 2:
+			return ECT_INTEGER16
+		case 4:
+			return ECT_INTEGER32
+		default:
+			return ECT_INTEGER64
+		}
+	case 6:
+		if a.Length == 8 {
+			return ECT_REAL64
+		}
+		return ECT_REAL32
+	default:
+		return dataTypeForBitLength(uint(a.Length) * 8)
+	}
+}
+
+func (m *Master) SoEReadAttribute(slave uint16, drive uint8, idn uint16) (SoEAttribute, error) {
+	data, err := m.SoERead(slave, drive, EC_SOE_ATTRIBUTE_B, idn, 4)
+	if err != nil {
+		return SoEAttribute{}, err
+	}
+	if len(data) < 4 {
+		return SoEAttribute{}, fmt.Errorf("short attribute of %s on slave %d", FormatIDN(idn), slave)
+	}
+	return decodeSoEAttribute(binary.LittleEndian.Uint32(data)), nil
+}
+
+// SoE strings and lists carry their current and maximum length in bytes
+func soeList(data []byte) []byte {
+	if len(data) < 4 {
+		return nil
+	}
+	l := int(binary.LittleEndian.Uint16(data))
+	if l > len(data)-4 {
+		l = len(data) - 4
+	}
+	return data[4 : 4+l]
+}
+
+func (m *Master) SoEReadName(slave uint16, drive uint8, idn uint16) (string, error) {
+	data, err := m.SoERead(slave, drive, EC_SOE_NAME_B, idn, C.EC_SOE_MAXNAME+4)
+	if err != nil {
+		return "", err
+	}
+	return string(soeList(data)), nil
+}
+
+func (m *Master) SoEReadUnit(slave uint16, drive uint8, idn uint16) (string, error) {
+	data, err := m.SoERead(slave, drive, EC_SOE_UNIT_B, idn, C.EC_SOE_MAXNAME+4)
+	if err != nil {
+		return "", err
+	}
+	return string(soeList(data)), nil
+}
+
+// Reads a list IDN, such as the AT and MDT configuration lists, as IDNs
+func (m *Master) SoEReadIDNList(slave uint16, drive uint8, idn uint16) ([]uint16, error) {
+	data, err := m.SoERead(slave, drive, EC_SOE_VALUE_B, idn, C.EC_SOE_MAXMAPPING*2+4)
+	if err != nil {
+		return nil, err
+	}
+
+	list := soeList(data)
+	idns := make([]uint16, len(list)/2)
+	for i := range idns {
+		idns[i] = binary.LittleEndian.Uint16(list[i*2:])
+	}
+	return idns, nil
+}
+
+// Process data sizes of an SoE slave in bits, as used by ConfigMap
+func (m *Master) ReadIDNMapSize(slave uint16) (outputBits, inputBits int, err error) {
+	if slave < 1 || slave > m.SlaveCount {
+		return 0, 0, fmt.Errorf("no slave %d", slave)
+	}
+
+	var osize, isize C.int
+	if C.ecx_readIDNmap(m.context, C.uint16(slave), &osize, &isize) <= 0 {
+		return 0, 0, fmt.Errorf("error reading IDN mapping of slave %d", slave)
+	}
+	return int(osize), int(isize), nil
+}
+
+// Reads the MDT (outputs) and AT (inputs) configuration lists of each drive
+// the SII lists. Every drive maps its control or status word ahead of the
+// listed IDNs.
+func (m *Master) readSoEPDOMapping(slave uint16) ([]*Variable, error) {
+	sii, err := m.ReadSII(slave)
+	if err != nil {
+		return nil, err
+	}
+	drives := uint8(1)
+	if sii.General != nil && sii.General.SoEChannels > 0 {
+		drives = sii.General.SoEChannels
+	}
+	if drives > EC_SOE_MAXDRIVES {
+		drives = EC_SOE_MAXDRIVES
+	}
+
+	var vars []*Variable
+	var outputOffset, inputOffset uint
+
+	for drive := uint8(0); drive < drives; drive++ {
+		for _, config := range []struct {
+			idn       uint16
+			direction PDODirection
+			header    string
+			offset    *uint
+		}{
+			{EC_IDN_MDTCONFIG, RxPDO, "Control word", &outputOffset},
+			{EC_IDN_ATCONFIG, TxPDO, "Status word", &inputOffset},
+		} {
+			idns, err := m.SoEReadIDNList(slave, drive, config.idn)
+			var soeErr *SoEError
+			if errors.As(err, &soeErr) && soeErr.ErrorCode == EC_SOE_ERR_NOIDN {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(idns) == 0 {
+				continue
+			}
+
+			driveName := fmt.Sprintf("Drive %d", drive)
+			vars = append(vars, &Variable{
+				Slave:     slave,
+				Direction: config.direction,
+				PDOIndex:  config.idn,
+				PDOName:   driveName,
+				Name:      config.header,
+				DataType:  ECT_UNSIGNED16,
+				BitOffset: *config.offset,
+				BitLength: 16,
+			})
+			*config.offset += 16
+
+			for _, idn := range idns {
+				attr, err := m.SoEReadAttribute(slave, drive, idn)
+				if err != nil {
+					return nil, err
+				}
+				if attr.List {
+					continue
+				}
+
+				name, err := m.SoEReadName(slave, drive, idn)
+				if err != nil || name == "" {
+					name = FormatIDN(idn)
+				}
+
+				v := &Variable{
+					Slave:     slave,
+					Direction: config.direction,
+					PDOIndex:  config.idn,
+					PDOName:   driveName,
+					Index:     idn,
+					Name:      name,
+					DataType:  attr.DataType(),
+					BitOffset: *config.offset,
+					BitLength: uint(attr.Length) * 8,
+				}
+				*config.offset += v.BitLength
+				vars = append(vars, v)
+			}
+		}
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,157 @@
+package soem
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Upper bounds of the histogram buckets. The last bucket is unbounded.
+var histogramBounds = []time.Duration{
+	1 * time.Microsecond,
+	2 * time.Microsecond,
+	5 * time.Microsecond,
+	10 * time.Microsecond,
+	20 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	200 * time.Microsecond,
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// Histogram is a fixed bucket duration histogram that can be recorded from
+// the cycle thread and read from any goroutine without locking
+type Histogram struct {
+	// accessed atomically, kept first for 64-bit alignment on 32-bit platforms
+	count   uint64
+	sum     uint64
+	min     uint64
+	max     uint64
+	buckets [17]uint64
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = -d
+	}
+	v := uint64(d)
+
+	i := 0
+	for i < len(histogramBounds) && d > histogramBounds[i] {
+		i++
+	}
+	atomic.AddUint64(&h.buckets[i], 1)
+	atomic.AddUint64(&h.sum, v)
+
+	// min is stored inverted so the zero value means no samples
+	for {
+		min := atomic.LoadUint64(&h.min)
+		if min != 0 && math.MaxUint64-min <= v {
+			break
+		}
+		if atomic.CompareAndSwapUint64(&h.min, min, math.MaxUint64-v) {
+			break
+		}
+	}
+	for {
+		max := atomic.LoadUint64(&h.max)
+		if max >= v || atomic.CompareAndSwapUint64(&h.max, max, v) {
+			break
+		}
+	}
+
+	atomic.AddUint64(&h.count, 1)
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Count:   atomic.LoadUint64(&h.count),
+		Max:     time.Duration(atomic.LoadUint64(&h.max)),
+		Buckets: make([]HistogramBucket, len(h.buckets)),
+	}
+	if min := atomic.LoadUint64(&h.min); min != 0 {
+		s.Min = time.Duration(math.MaxUint64 - min)
+	}
+	if s.Count > 0 {
+		s.Mean = time.Duration(atomic.LoadUint64(&h.sum) / s.Count)
+	}
+
+	for i := range h.buckets {
+		s.Buckets[i].Count = atomic.LoadUint64(&h.buckets[i])
+		if i < len(histogramBounds) {
+			s.Buckets[i].UpperBound = histogramBounds[i]
+		} else {
+			s.Buckets[i].UpperBound = time.Duration(math.MaxInt64)
+		}
+	}
+
+	return s
+}
+
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+type HistogramSnapshot struct {
+	Count   uint64
+	Min     time.Duration
+	Max     time.Duration
+	Mean    time.Duration
+	Buckets []HistogramBucket
+}
+
+func (s HistogramSnapshot) String() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "n %d min %s mean %s max %s\n", s.Count, s.Min, s.Mean, s.Max)
+	for _, bucket := range s.Buckets {
+		if bucket.Count == 0 {
+			continue
+		}
+		if bucket.UpperBound == time.Duration(math.MaxInt64) {
+			fmt.Fprintf(&b, "  >%-8s %d\n", histogramBounds[len(histogramBounds)-1], bucket.Count)
+		} else {
+			fmt.Fprintf(&b, "  <=%-7s %d\n", bucket.UpperBound, bucket.Count)
+		}
+	}
+	return b.String()
+}
+
+// Timing of a Cycle, as returned by Cycle.Stats
+type CycleStats struct {
+	Cycles   uint64
+	Overruns uint64
+	// Exchanges of the group with a working counter below its expected
+	// value, as counted by the master's WKC monitor
+	WKCErrors uint64
+	LastWKC   int
+
+	// How late the cycle thread woke after each deadline
+	Latency HistogramSnapshot
+	// Deviation of the interval between wake-ups from the period
+	Jitter HistogramSnapshot
+	// Time from waking to having received the frame sent in the previous
+	// cycle
+	RoundTrip HistogramSnapshot
+	// Time from waking to sending the next frame
+	Execution HistogramSnapshot
+}
+
+func (s CycleStats) String() string {
+	return fmt.Sprintf("Cycles %d, overruns %d, WKC errors %d, last WKC %d\n"+
+		"Latency %s"+
+		"Jitter %s"+
+		"Round trip %s"+
+		"Execution %s",
+		s.Cycles, s.Overruns, s.WKCErrors, s.LastWKC,
+		s.Latency, s.Jitter, s.RoundTrip, s.Execution)
+}
@@ -0,0 +1,88 @@
+package soem
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		bucket int
+	}{
+		{0, 0},
+		{time.Microsecond, 0},
+		{time.Microsecond + 1, 1},
+		{2 * time.Microsecond, 1},
+		{3 * time.Microsecond, 2},
+		{-3 * time.Microsecond, 2},
+		{10 * time.Microsecond, 3},
+		{999 * time.Microsecond, 9},
+		{time.Millisecond, 9},
+		{100 * time.Millisecond, 15},
+		{100*time.Millisecond + 1, 16},
+		{time.Hour, 16},
+	}
+
+	for _, tt := range tests {
+		h := Histogram{}
+		h.Record(tt.d)
+		s := h.Snapshot()
+		for i, b := range s.Buckets {
+			want := uint64(0)
+			if i == tt.bucket {
+				want = 1
+			}
+			if b.Count != want {
+				t.Errorf("Record(%s): bucket %d (<= %s) count %d, want %d", tt.d, i, b.UpperBound, b.Count, want)
+			}
+		}
+	}
+}
+
+func TestHistogramSnapshot(t *testing.T) {
+	h := Histogram{}
+	s := h.Snapshot()
+	if s.Count != 0 || s.Min != 0 || s.Max != 0 || s.Mean != 0 {
+		t.Errorf("empty snapshot = %+v", s)
+	}
+
+	for _, d := range []time.Duration{4 * time.Microsecond, 0, 11 * time.Microsecond, 5 * time.Microsecond} {
+		h.Record(d)
+	}
+	s = h.Snapshot()
+
+	if s.Count != 4 {
+		t.Errorf("Count = %d, want 4", s.Count)
+	}
+	if s.Min != 0 {
+		t.Errorf("Min = %s, want 0s", s.Min)
+	}
+	if s.Max != 11*time.Microsecond {
+		t.Errorf("Max = %s, want 11µs", s.Max)
+	}
+	if s.Mean != 5*time.Microsecond {
+		t.Errorf("Mean = %s, want 5µs", s.Mean)
+	}
+
+	if len(s.Buckets) != len(histogramBounds)+1 {
+		t.Fatalf("%d buckets, want %d", len(s.Buckets), len(histogramBounds)+1)
+	}
+	for i, bound := range histogramBounds {
+		if s.Buckets[i].UpperBound != bound {
+			t.Errorf("bucket %d upper bound %s, want %s", i, s.Buckets[i].UpperBound, bound)
+		}
+	}
+	if last := s.Buckets[len(s.Buckets)-1].UpperBound; last != time.Duration(math.MaxInt64) {
+		t.Errorf("last bucket upper bound %s, want unbounded", last)
+	}
+
+	total := uint64(0)
+	for _, b := range s.Buckets {
+		total += b.Count
+	}
+	if total != s.Count {
+		t.Errorf("bucket counts sum to %d, want %d", total, s.Count)
+	}
+}
@@ -0,0 +1,178 @@
+package soem
+
+/*
+#cgo LDFLAGS: -lsoem
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <soem/ethercat.h>
+
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type SlaveEventType uint8
+
+const (
+	SlaveLost SlaveEventType = iota
+	SlaveFound
+	SlaveRecovered
+	SlaveReconfigured
+	SlaveErrorAcknowledged
+	SlaveOperational
+	AllSlavesOperational
+)
+
+func (t SlaveEventType) String() string {
+	switch t {
+	case SlaveLost:
+		return "lost"
+	case SlaveFound:
+		return "found"
+	case SlaveRecovered:
+		return "recovered"
+	case SlaveReconfigured:
+		return "reconfigured"
+	case SlaveErrorAcknowledged:
+		return "error acknowledged"
+	case SlaveOperational:
+		return "operational"
+	case AllSlavesOperational:
+		return "all slaves operational"
+	default:
+		return fmt.Sprintf("%d", int(t))
+	}
+}
+
+type SlaveEvent struct {
+	Time  time.Time
+	Type  SlaveEventType
+	Slave uint16
+	State EtherCATState
+}
+
+func (e SlaveEvent) String() string {
+	if e.Type == AllSlavesOperational {
+		return e.Type.String()
+	}
+	return fmt.Sprintf("slave %d %s (%s)", e.Slave, e.Type, e.State)
+}
+
+// Supervisor watches the working counter and slave states of a group that
+// has been brought to OP, and brings slaves that drop out back to OP. It is
+// modelled on the ecatcheck loop from the SOEM examples.
+type Supervisor struct {
+	// Time between supervision passes, 10 ms by default
+	Interval time.Duration
+
+	master *Master
+	group  uint8
+
+	Events chan SlaveEvent
+}
+
+func NewSupervisor(master *Master, group uint8) (*Supervisor, error) {
+	if err := checkGroup(group); err != nil {
+		return nil, err
+	}
+
+	return &Supervisor{
+		Interval: 10 * time.Millisecond,
+		master:   master,
+		group:    group,
+		Events:   make(chan SlaveEvent, 32),
+	}, nil
+}
+
+// Runs the supervisor until ctx is cancelled. Start it once the group has
+// reached OP and process data is being exchanged.
+func (s *Supervisor) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Performs a single supervision pass
+func (s *Supervisor) Check() {
+	m := s.master
+	cgroup := m.ecGroup(s.group)
+
+	if m.lastWKCOf(s.group) >= m.expectedWKC(s.group) && cgroup.docheckstate == 0 {
+		return
+	}
+
+	// one or more slaves are not responding
+	cgroup.docheckstate = 0
+	m.ReadState()
+
+	for slave := uint16(1); slave <= m.SlaveCount; slave++ {
+		cslave := m.ecSlave(slave)
+		inGroup := s.group == 0 || uint8(cslave.group) == s.group
+		if inGroup && EtherCATState(cslave.state) != EC_STATE_OPERATIONAL {
+			cgroup.docheckstate = 1
+			state := EtherCATState(cslave.state)
+
+			switch {
+			case state == EC_STATE_SAFE_OP+EC_STATE_ERROR:
+				cslave.state = C.uint16(EC_STATE_SAFE_OP + EC_STATE_ACK)
+				C.ecx_writestate(m.context, C.ushort(slave))
+				s.emit(SlaveErrorAcknowledged, slave, state)
+			case state == EC_STATE_SAFE_OP:
+				cslave.state = C.uint16(EC_STATE_OPERATIONAL)
+				C.ecx_writestate(m.context, C.ushort(slave))
+				s.emit(SlaveOperational, slave, state)
+			case state > EC_STATE_NONE:
+				if state, err := m.ReconfigSlave(slave, EC_TIMEOUTMON); err == nil {
+					cslave.islost = 0
+					s.emit(SlaveReconfigured, slave, state)
+				}
+			case cslave.islost == 0:
+				// re-check state
+				C.ecx_statecheck(m.context, C.ushort(slave), C.ushort(EC_STATE_OPERATIONAL), C.int(EC_TIMEOUTRET))
+				if EtherCATState(cslave.state) == EC_STATE_NONE {
+					cslave.islost = 1
+					s.emit(SlaveLost, slave, EC_STATE_NONE)
+				}
+			}
+		}
+
+		if cslave.islost != 0 {
+			if EtherCATState(cslave.state) == EC_STATE_NONE {
+				if err := m.RecoverSlave(slave, EC_TIMEOUTMON); err == nil {
+					cslave.islost = 0
+					s.emit(SlaveRecovered, slave, EtherCATState(cslave.state))
+				}
+			} else {
+				cslave.islost = 0
+				s.emit(SlaveFound, slave, EtherCATState(cslave.state))
+			}
+		}
+	}
+
+	if cgroup.docheckstate == 0 {
+		s.emit(AllSlavesOperational, 0, EC_STATE_OPERATIONAL)
+	}
+}
+
+func (s *Supervisor) emit(t SlaveEventType, slave uint16, state EtherCATState) {
+	select {
+	case s.Events <- SlaveEvent{time.Now(), t, slave, state}:
+	default:
+	}
+}
@@ -0,0 +1,139 @@
+package soem
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A slave in the network tree. The root node stands for the master and has
+// no slave.
+type TopologyNode struct {
+	Slave    *Slave
+	Children []*TopologyNode
+}
+
+func (n *TopologyNode) label() string {
+	if n.Slave == nil {
+		return "Master"
+	}
+	return fmt.Sprintf("%d %s", n.Slave.Position, n.Slave.Name)
+}
+
+// ESC ports in the order frames are forwarded to them after port 0
+var portOrder = [...]uint8{3, 1, 2, 0}
+
+// Works out each slave's entry port and the port of its parent it is
+// connected to. SOEM only fills these in ecx_configdc and only for DC
+// slaves, so they are derived here from the active ports instead, using
+// the same port consumption as SOEM's parent search: frames enter through
+// port 0, or the lowest active port if port 0 is down, and a parent's
+// children in position order take its remaining ports in forwarding order.
+func (m *Master) assignPorts() {
+	free := make([]uint8, len(m.Slaves)+1)
+
+	for i, s := range m.Slaves {
+		s.EntryPort = 0
+		for port := uint8(0); port < 4; port++ {
+			if s.ActivePorts&(1<<port) != 0 {
+				s.EntryPort = port
+				break
+			}
+		}
+		free[i+1] = s.ActivePorts &^ (1 << s.EntryPort)
+
+		s.ParentPort = 0
+		p := int(s.Parent)
+		if p < 1 || p >= len(free) {
+			continue
+		}
+		for _, port := range portOrder {
+			if free[p]&(1<<port) != 0 {
+				s.ParentPort = port
+				free[p] &^= 1 << port
+				break
+			}
+		}
+	}
+}
+
+// Builds the network tree from the parent found by ConfigInit and the ports
+// derived from each slave's active ports. Children are ordered by the parent port they connect to.
+func (m *Master) Topology() *TopologyNode {
+	root := &TopologyNode{}
+	nodes := make([]*TopologyNode, len(m.Slaves)+1)
+	nodes[0] = root
+
+	for i, s := range m.Slaves {
+		nodes[i+1] = &TopologyNode{Slave: s}
+	}
+
+	for _, node := range nodes[1:] {
+		parent := root
+		if p := int(node.Slave.Parent); p > 0 && p < len(nodes) {
+			parent = nodes[p]
+		}
+
+		// keep children sorted by parent port, slaves arrive in position
+		// order so equal ports keep their order
+		i := len(parent.Children)
+		for i > 0 && parent.Children[i-1].Slave.ParentPort > node.Slave.ParentPort {
+			i--
+		}
+		parent.Children = append(parent.Children, nil)
+		copy(parent.Children[i+1:], parent.Children[i:])
+		parent.Children[i] = node
+	}
+
+	return root
+}
+
+// Renders the tree as indented text, one connection per line, e.g.
+// "1 EK1100 port 1 → 2 EL1008 port 0"
+func (n *TopologyNode) String() string {
+	b := strings.Builder{}
+	b.WriteString(n.label() + "\n")
+	n.writeText(&b, 1)
+	return b.String()
+}
+
+func (n *TopologyNode) writeText(b *strings.Builder, depth int) {
+	for _, c := range n.Children {
+		from := n.label()
+		if n.Slave != nil {
+			from += fmt.Sprintf(" port %d", c.Slave.ParentPort)
+		}
+		fmt.Fprintf(b, "%s%s → %s port %d\n",
+			strings.Repeat("  ", depth), from, c.label(), c.Slave.EntryPort)
+		c.writeText(b, depth+1)
+	}
+}
+
+// Renders the tree in Graphviz DOT format with port numbers on the edges
+func (n *TopologyNode) DOT() string {
+	b := strings.Builder{}
+	b.WriteString("digraph ethercat {\n")
+	b.WriteString("  node [shape=box];\n")
+	n.writeDOT(&b)
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (n *TopologyNode) dotID() string {
+	if n.Slave == nil {
+		return "master"
+	}
+	return fmt.Sprintf("slave%d", n.Slave.Position)
+}
+
+func (n *TopologyNode) writeDOT(b *strings.Builder) {
+	fmt.Fprintf(b, "  %s [label=%q];\n", n.dotID(), n.label())
+	for _, c := range n.Children {
+		tail := ""
+		if n.Slave != nil {
+			tail = fmt.Sprintf("%d", c.Slave.ParentPort)
+		}
+		fmt.Fprintf(b, "  %s -> %s [taillabel=%q, headlabel=%q];\n",
+			n.dotID(), c.dotID(), tail, fmt.Sprintf("%d", c.Slave.EntryPort))
+		c.writeDOT(b)
+	}
+}
@@ -11,6 +11,8 @@ package soem
 import "C"
 import (
 	"fmt"
+
+	"github.com/siyka-au/go-soem/ectype"
 )
 
 const (
@@ -51,6 +53,8 @@ const (
 	EC_TIMEOUTRXM = 700000
 	/** timeout value in us for check statechange */
 	EC_TIMEOUTSTATE = 2000000
+	/** timeout value in us for slave monitoring and recovery */
+	EC_TIMEOUTMON = 500
 	/** size of EEPROM bitmap cache */
 	EC_MAXEEPBITMAP = 128
 	/** size of EEPROM cache buffer */
@@ -59,6 +63,8 @@ const (
 	EC_DEFAULTRETRIES = 3
 	/** default group size in 2^x */
 	EC_LOGGROUPOFFSET = 16
+	/** size of mailbox buffer in bytes */
+	EC_MAXMBX = 1486
 )
 
 type EtherCATError uint16
@@ -94,6 +100,35 @@ const (
 	EC_ERR_TYPE_EOE_INVALID_RX_DATA EtherCATErrorType = 11
 )
 
+func (t EtherCATErrorType) String() string {
+	switch t {
+	case EC_ERR_TYPE_SDO_ERROR:
+		return "EC_ERR_TYPE_SDO_ERROR"
+	case EC_ERR_TYPE_EMERGENCY:
+		return "EC_ERR_TYPE_EMERGENCY"
+	case EC_ERR_TYPE_PACKET_ERROR:
+		return "EC_ERR_TYPE_PACKET_ERROR"
+	case EC_ERR_TYPE_SDOINFO_ERROR:
+		return "EC_ERR_TYPE_SDOINFO_ERROR"
+	case EC_ERR_TYPE_FOE_ERROR:
+		return "EC_ERR_TYPE_FOE_ERROR"
+	case EC_ERR_TYPE_FOE_BUF2SMALL:
+		return "EC_ERR_TYPE_FOE_BUF2SMALL"
+	case EC_ERR_TYPE_FOE_PACKETNUMBER:
+		return "EC_ERR_TYPE_FOE_PACKETNUMBER"
+	case EC_ERR_TYPE_SOE_ERROR:
+		return "EC_ERR_TYPE_SOE_ERROR"
+	case EC_ERR_TYPE_MBX_ERROR:
+		return "EC_ERR_TYPE_MBX_ERROR"
+	case EC_ERR_TYPE_FOE_FILE_NOTFOUND:
+		return "EC_ERR_TYPE_FOE_FILE_NOTFOUND"
+	case EC_ERR_TYPE_EOE_INVALID_RX_DATA:
+		return "EC_ERR_TYPE_EOE_INVALID_RX_DATA"
+	default:
+		return fmt.Sprintf("%d", int(t))
+	}
+}
+
 type EtherCATCommandType uint16
 
 const (
@@ -139,36 +174,66 @@ const (
 	EC_ECMD_RELOAD EtherCATEEPROMCommandType = 0x0300
 )
 
-type EtherCATDataType uint16
+type EtherCATDataType = ectype.DataType
+
+const (
+	ECT_BOOLEAN         = ectype.ECT_BOOLEAN
+	ECT_INTEGER8        = ectype.ECT_INTEGER8
+	ECT_INTEGER16       = ectype.ECT_INTEGER16
+	ECT_INTEGER32       = ectype.ECT_INTEGER32
+	ECT_UNSIGNED8       = ectype.ECT_UNSIGNED8
+	ECT_UNSIGNED16      = ectype.ECT_UNSIGNED16
+	ECT_UNSIGNED32      = ectype.ECT_UNSIGNED32
+	ECT_REAL32          = ectype.ECT_REAL32
+	ECT_VISIBLE_STRING  = ectype.ECT_VISIBLE_STRING
+	ECT_OCTET_STRING    = ectype.ECT_OCTET_STRING
+	ECT_UNICODE_STRING  = ectype.ECT_UNICODE_STRING
+	ECT_TIME_OF_DAY     = ectype.ECT_TIME_OF_DAY
+	ECT_TIME_DIFFERENCE = ectype.ECT_TIME_DIFFERENCE
+	ECT_DOMAIN          = ectype.ECT_DOMAIN
+	ECT_INTEGER24       = ectype.ECT_INTEGER24
+	ECT_REAL64          = ectype.ECT_REAL64
+	ECT_INTEGER64       = ectype.ECT_INTEGER64
+	ECT_UNSIGNED24      = ectype.ECT_UNSIGNED24
+	ECT_UNSIGNED64      = ectype.ECT_UNSIGNED64
+	ECT_BIT1            = ectype.ECT_BIT1
+	ECT_BIT2            = ectype.ECT_BIT2
+	ECT_BIT3            = ectype.ECT_BIT3
+	ECT_BIT4            = ectype.ECT_BIT4
+	ECT_BIT5            = ectype.ECT_BIT5
+	ECT_BIT6            = ectype.ECT_BIT6
+	ECT_BIT7            = ectype.ECT_BIT7
+	ECT_BIT8            = ectype.ECT_BIT8
+)
+
+// Parses a data type named as in ESI and ENI files
+func ParseDataType(name string) (EtherCATDataType, bool) {
+	return ectype.ParseDataType(name)
+}
+
+type MailboxProtocol = ectype.MailboxProtocol
+
+const (
+	ECT_MBXPROT_AOE = ectype.ECT_MBXPROT_AOE
+	ECT_MBXPROT_EOE = ectype.ECT_MBXPROT_EOE
+	ECT_MBXPROT_COE = ectype.ECT_MBXPROT_COE
+	ECT_MBXPROT_FOE = ectype.ECT_MBXPROT_FOE
+	ECT_MBXPROT_SOE = ectype.ECT_MBXPROT_SOE
+	ECT_MBXPROT_VOE = ectype.ECT_MBXPROT_VOE
+)
+
+type SIICategory uint16
 
 const (
-	ECT_BOOLEAN         EtherCATDataType = 0x0001
-	ECT_INTEGER8        EtherCATDataType = 0x0002
-	ECT_INTEGER16       EtherCATDataType = 0x0003
-	ECT_INTEGER32       EtherCATDataType = 0x0004
-	ECT_UNSIGNED8       EtherCATDataType = 0x0005
-	ECT_UNSIGNED16      EtherCATDataType = 0x0006
-	ECT_UNSIGNED32      EtherCATDataType = 0x0007
-	ECT_REAL32          EtherCATDataType = 0x0008
-	ECT_VISIBLE_STRING  EtherCATDataType = 0x0009
-	ECT_OCTET_STRING    EtherCATDataType = 0x000A
-	ECT_UNICODE_STRING  EtherCATDataType = 0x000B
-	ECT_TIME_OF_DAY     EtherCATDataType = 0x000C
-	ECT_TIME_DIFFERENCE EtherCATDataType = 0x000D
-	ECT_DOMAIN          EtherCATDataType = 0x000F
-	ECT_INTEGER24       EtherCATDataType = 0x0010
-	ECT_REAL64          EtherCATDataType = 0x0011
-	ECT_INTEGER64       EtherCATDataType = 0x0015
-	ECT_UNSIGNED24      EtherCATDataType = 0x0016
-	ECT_UNSIGNED64      EtherCATDataType = 0x001B
-	ECT_BIT1            EtherCATDataType = 0x0030
-	ECT_BIT2            EtherCATDataType = 0x0031
-	ECT_BIT3            EtherCATDataType = 0x0032
-	ECT_BIT4            EtherCATDataType = 0x0033
-	ECT_BIT5            EtherCATDataType = 0x0034
-	ECT_BIT6            EtherCATDataType = 0x0035
-	ECT_BIT7            EtherCATDataType = 0x0036
-	ECT_BIT8            EtherCATDataType = 0x0037
+	ECT_SII_NOP     SIICategory = 0
+	ECT_SII_STRING  SIICategory = 10
+	ECT_SII_GENERAL SIICategory = 30
+	ECT_SII_FMMU    SIICategory = 40
+	ECT_SII_SM      SIICategory = 41
+	ECT_SII_TXPDO   SIICategory = 50
+	ECT_SII_RXPDO   SIICategory = 51
+	ECT_SII_DC      SIICategory = 60
+	ECT_SII_END     SIICategory = 0xffff
 )
 
 type EtherCATState uint8
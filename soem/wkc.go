@@ -0,0 +1,113 @@
+package soem
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// How working counter misses of a group are handled
+type WKCPolicy struct {
+	// Consecutive misses before a fault is raised, 0 is treated as 1
+	Threshold uint64
+	// Receives a fault event when Threshold is reached and a recovery event
+	// at the first complete exchange afterwards. Sends do not block.
+	Events chan<- WKCEvent
+}
+
+type WKCEvent struct {
+	Time  time.Time
+	Group uint8
+	// True when raised by reaching the threshold, false on recovery
+	Fault       bool
+	Expected    int
+	Actual      int
+	Consecutive uint64
+}
+
+func (e WKCEvent) String() string {
+	if e.Fault {
+		return fmt.Sprintf("group %d WKC fault: %d of %d expected, %d consecutive misses",
+			e.Group, e.Actual, e.Expected, e.Consecutive)
+	}
+	return fmt.Sprintf("group %d WKC recovered: %d of %d expected", e.Group, e.Actual, e.Expected)
+}
+
+type WKCStatus struct {
+	Expected int
+	Last     int
+	// Exchanges with a working counter below Expected
+	Misses      uint64
+	Consecutive uint64
+	Faulted     bool
+}
+
+type wkcMonitor struct {
+	// accessed atomically
+	misses      uint64
+	consecutive uint64
+	faulted     uint32
+
+	policy WKCPolicy
+}
+
+// Sets how working counter misses of group are handled. Must be set before
+// process data is exchanged.
+func (m *Master) SetWKCPolicy(group uint8, policy WKCPolicy) error {
+	if err := checkGroup(group); err != nil {
+		return err
+	}
+	m.wkcMonitors[group].policy = policy
+	return nil
+}
+
+func (m *Master) WKCStatus(group uint8) (WKCStatus, error) {
+	if err := checkGroup(group); err != nil {
+		return WKCStatus{}, err
+	}
+
+	mon := &m.wkcMonitors[group]
+	return WKCStatus{
+		Expected:    m.expectedWKC(group),
+		Last:        m.lastWKCOf(group),
+		Misses:      atomic.LoadUint64(&mon.misses),
+		Consecutive: atomic.LoadUint64(&mon.consecutive),
+		Faulted:     atomic.LoadUint32(&mon.faulted) != 0,
+	}, nil
+}
+
+// Compares the working counter of an exchange with the group's expected
+// value, called for every ReceiveProcessDataWithGroup
+func (m *Master) checkWKC(group uint8, wkc int) {
+	mon := &m.wkcMonitors[group]
+	expected := m.expectedWKC(group)
+
+	if wkc >= expected {
+		atomic.StoreUint64(&mon.consecutive, 0)
+		if atomic.CompareAndSwapUint32(&mon.faulted, 1, 0) {
+			mon.emit(WKCEvent{time.Now(), group, false, expected, wkc, 0})
+		}
+		return
+	}
+
+	atomic.AddUint64(&mon.misses, 1)
+	consecutive := atomic.AddUint64(&mon.consecutive, 1)
+
+	threshold := mon.policy.Threshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	if consecutive >= threshold && atomic.CompareAndSwapUint32(&mon.faulted, 0, 1) {
+		mon.emit(WKCEvent{time.Now(), group, true, expected, wkc, consecutive})
+	}
+}
+
+func (mon *wkcMonitor) emit(e WKCEvent) {
+	if mon.policy.Events == nil {
+		return
+	}
+	select {
+	case mon.policy.Events <- e:
+	default:
+	}
+}